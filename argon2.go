@@ -1,104 +1,286 @@
 package password
 
 import (
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
-// Argon2Params Argon2id parameters
+// Argon2Params Argon2 parameters, for argon2id, argon2i and argon2d
 type Argon2Params struct {
-	memory      uint32
-	iterations  uint32
-	parallelism uint8
-	saltLength  int
-	keyLength   uint32
+	// Memory: memory in KiB, at least 8*Parallelism
+	Memory uint32 `json:"memory"`
+	// Iterations: number of passes over the memory, at least 1
+	Iterations uint32 `json:"iterations"`
+	// Parallelism: number of threads, at least 1
+	Parallelism uint8 `json:"parallelism"`
+	// SaltLength: length of random salt in bytes, at least 8
+	SaltLength int `json:"salt_length"`
+	// KeyLength: length of hash in bytes, at least 16
+	KeyLength uint32 `json:"key_length"`
 }
 
+const (
+	minArgon2SaltLength = 8
+	minArgon2KeyLength  = 16
+)
+
 var defaultArgon2Params = &Argon2Params{
-	memory:      64 * 1024,
-	iterations:  1,
-	parallelism: 4,
-	saltLength:  16,
-	keyLength:   32,
+	Memory:      64 * 1024,
+	Iterations:  1,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// djangoArgon2Params are the params of Django's Argon2PasswordHasher, whose
+// salt is 22 random characters and hash is 16 bytes.
+var djangoArgon2Params = &Argon2Params{
+	Memory:      102400,
+	Iterations:  2,
+	Parallelism: 8,
+	SaltLength:  22,
+	KeyLength:   16,
+}
+
+func (p *Argon2Params) validate() error {
+	if p.Iterations < 1 {
+		return errIllegalParam("iterations", "should be at least 1")
+	}
+	if p.Parallelism < 1 {
+		return errIllegalParam("parallelism", "should be at least 1")
+	}
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return errIllegalParam("memory", "should be at least 8*parallelism")
+	}
+	if p.SaltLength < minArgon2SaltLength {
+		return errIllegalParam("salt_length", "should be at least "+strconv.Itoa(minArgon2SaltLength))
+	}
+	if p.KeyLength < minArgon2KeyLength {
+		return errIllegalParam("key_length", "should be at least "+strconv.Itoa(minArgon2KeyLength))
+	}
+	return nil
+}
+
+// weakerThan reports whether any cost of p is lower than other, parallelism
+// is not a cost because it only splits the same memory into lanes.
+func (p *Argon2Params) weakerThan(other *Argon2Params) bool {
+	return p.Memory < other.Memory ||
+		p.Iterations < other.Iterations ||
+		p.SaltLength < other.SaltLength ||
+		p.KeyLength < other.KeyLength
+}
+
+// parseArgon2Params parses `HasherOption.Params`, which may be *Argon2Params,
+// Argon2Params or anything decodable from JSON, such as map[string]interface{}.
+// Missing fields are filled by defaults.
+func parseArgon2Params(v interface{}, defaults *Argon2Params) (*Argon2Params, error) {
+	var params *Argon2Params
+	switch p := v.(type) {
+	case nil:
+		return defaults, nil
+	case *Argon2Params:
+		params = p
+	case Argon2Params:
+		params = &p
+	default:
+		params = &Argon2Params{}
+		*params = *defaults
+		if err := decodeParams(v, params); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// argon2KeyFuncs derives keys for argon2 variants. argon2id is recommended,
+// argon2i and argon2d are supported to verify and migrate existing passwords.
+var argon2KeyFuncs = map[string]func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte{
+	argon2Algo:  argon2.IDKey,
+	argon2iAlgo: argon2.Key,
+	argon2dAlgo: argon2dKey,
+}
+
+// djangoArgon2Prefix prefixes PHC strings of argon2 in FormatDjango:
+//
+//	argon2$argon2id$v=19$m=102400,t=2,p=8$<b64salt>$<b64hash>
+const djangoArgon2Prefix = "argon2"
+
+func isDjangoArgon2(encoded string) bool {
+	return strings.HasPrefix(encoded, djangoArgon2Prefix+sep)
 }
 
 type argon2Hasher struct {
+	algo   string
+	format string
 	params *Argon2Params
 }
 
 func (hasher *argon2Hasher) Encode(password string) (string, error) {
-	salt, err := generateRandomBytes(hasher.params.saltLength)
+	salt, err := generateRandomBytes(hasher.params.SaltLength)
 	if err != nil {
 		return "", err
 	}
-	return hasher.encode(password, salt, hasher.params)
+	return hasher.encode(hasher.algo, hasher.format, password, salt, hasher.params)
 }
 
-func (hasher *argon2Hasher) encode(password string, salt []byte, params *Argon2Params) (string, error) {
-	hash := argon2.IDKey(
+func (hasher *argon2Hasher) encode(algo, format, password string, salt []byte, params *Argon2Params) (string, error) {
+	keyFunc, ok := argon2KeyFuncs[algo]
+	if !ok {
+		return "", errUnknownAlgorithmOf(algo)
+	}
+	hash := keyFunc(
 		[]byte(password),
 		salt,
-		params.iterations,
-		params.memory,
-		params.parallelism,
-		params.keyLength)
+		params.Iterations,
+		params.Memory,
+		params.Parallelism,
+		params.KeyLength)
+
+	if format == FormatPHC || format == FormatDjango {
+		p := fmt.Sprintf("m=%d,t=%d,p=%d", params.Memory, params.Iterations, params.Parallelism)
+		phc := formatPHC(phcIdentifierOf(algo), strconv.Itoa(argon2.Version), p, salt, hash)
+		if format == FormatDjango {
+			return djangoArgon2Prefix + phc, nil
+		}
+		return phc, nil
+	}
 
 	p := []string{
-		argon2Algo,
+		algo,
 		hex.EncodeToString(salt),
-		strconv.Itoa(int(params.iterations)),
-		strconv.Itoa(int(params.memory)),
-		strconv.Itoa(int(params.parallelism)),
-		strconv.Itoa(int(params.keyLength)),
+		strconv.Itoa(int(params.Iterations)),
+		strconv.Itoa(int(params.Memory)),
+		strconv.Itoa(int(params.Parallelism)),
+		strconv.Itoa(int(params.KeyLength)),
 		hex.EncodeToString(hash),
 	}
 	return strings.Join(p, sep), nil
 }
 
 func (hasher *argon2Hasher) Decode(encoded string) (*PasswordInfo, error) {
+	if isPHC(encoded) {
+		return hasher.decodePHC(encoded)
+	}
+	if isDjangoArgon2(encoded) {
+		return hasher.decodePHC(encoded[len(djangoArgon2Prefix):])
+	}
+
 	parts := strings.SplitN(encoded, sep, 7)
-	if parts[0] != argon2Algo {
-		return nil, errUnknownAlgorithm
+	if _, ok := argon2KeyFuncs[parts[0]]; !ok {
+		return nil, errUnknownAlgorithmOf(parts[0])
 	}
 
-	iter, err := strconv.Atoi(parts[2])
+	if len(parts) != 7 {
+		return nil, ErrMalformedEncoded
+	}
+
+	iter, err := strconv.ParseUint(parts[2], 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("iterations", err)
 	}
 
-	memory, err := strconv.Atoi(parts[3])
+	memory, err := strconv.ParseUint(parts[3], 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("memory", err)
 	}
 
-	parallelism, err := strconv.Atoi(parts[4])
+	parallelism, err := strconv.ParseUint(parts[4], 10, 8)
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("parallelism", err)
 	}
 
-	keyLength, err := strconv.Atoi(parts[5])
+	keyLength, err := strconv.ParseUint(parts[5], 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("key_length", err)
+	}
+
+	if iter == 0 {
+		return nil, errIllegalParam("iterations", "should be at least 1")
+	}
+	if parallelism == 0 {
+		return nil, errIllegalParam("parallelism", "should be at least 1")
 	}
 
 	salt, err := hex.DecodeString(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("salt", err)
+	}
+	hash, err := hex.DecodeString(parts[6])
+	if err != nil || len(hash) == 0 {
+		return nil, errMalformed("hash", err)
 	}
 	return &PasswordInfo{
 		Algorithm:  parts[0],
 		Hash:       parts[6],
 		Salt:       parts[1],
-		Iterations: iter,
+		Iterations: int(iter),
 		Others: &Argon2Params{
-			memory:      uint32(memory),
-			iterations:  uint32(iter),
-			parallelism: uint8(parallelism),
-			saltLength:  len(salt),
-			keyLength:   uint32(keyLength),
+			Memory:      uint32(memory),
+			Iterations:  uint32(iter),
+			Parallelism: uint8(parallelism),
+			SaltLength:  len(salt),
+			KeyLength:   uint32(keyLength),
+		},
+	}, nil
+}
+
+// decodePHC decodes `$argon2id$v=19$m=65536,t=1,p=4$<b64salt>$<b64hash>`.
+// Salt and Hash of PasswordInfo are hex encoded as the default format.
+func (hasher *argon2Hasher) decodePHC(encoded string) (*PasswordInfo, error) {
+	p, err := parsePHC(encoded)
+	if err != nil {
+		return nil, err
+	}
+	algo := phcIdentifiers[p.id]
+	if _, ok := argon2KeyFuncs[algo]; !ok {
+		return nil, errUnknownAlgorithmOf(p.id)
+	}
+	if p.version != strconv.Itoa(argon2.Version) {
+		return nil, errIllegalParam("v", "should be "+strconv.Itoa(argon2.Version))
+	}
+
+	memory, err := p.uintParam("m", 32)
+	if err != nil {
+		return nil, err
+	}
+	iter, err := p.uintParam("t", 32)
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := p.uintParam("p", 8)
+	if err != nil {
+		return nil, err
+	}
+	if iter == 0 {
+		return nil, errIllegalParam("t", "should be at least 1")
+	}
+	if parallelism == 0 {
+		return nil, errIllegalParam("p", "should be at least 1")
+	}
+	if len(p.hash) == 0 {
+		return nil, errMalformed("hash", nil)
+	}
+
+	return &PasswordInfo{
+		Algorithm:  algo,
+		Hash:       hex.EncodeToString(p.hash),
+		Salt:       hex.EncodeToString(p.salt),
+		Iterations: int(iter),
+		Others: &Argon2Params{
+			Memory:      uint32(memory),
+			Iterations:  uint32(iter),
+			Parallelism: uint8(parallelism),
+			SaltLength:  len(p.salt),
+			KeyLength:   uint32(len(p.hash)),
 		},
 	}, nil
 }
@@ -109,12 +291,23 @@ func (hasher *argon2Hasher) Verify(password, encoded string) bool {
 		return false
 	}
 	params := pi.Others.(*Argon2Params)
-	salt, _ := hex.DecodeString(pi.Salt)
-	encoded2, err := hasher.encode(password, salt, params)
+	salt, err := hex.DecodeString(pi.Salt)
 	if err != nil {
 		return false
 	}
-	return encoded2 == encoded
+	hash, err := hex.DecodeString(pi.Hash)
+	if err != nil || len(hash) == 0 {
+		return false
+	}
+
+	key := argon2KeyFuncs[pi.Algorithm](
+		[]byte(password),
+		salt,
+		params.Iterations,
+		params.Memory,
+		params.Parallelism,
+		uint32(len(hash)))
+	return subtle.ConstantTimeCompare(key, hash) == 1
 }
 
 func (hasher *argon2Hasher) MustUpdate(encoded string) bool {
@@ -122,26 +315,32 @@ func (hasher *argon2Hasher) MustUpdate(encoded string) bool {
 	if err != nil {
 		return false
 	}
-	p := pi.Others.(*Argon2Params)
-	return *p != *hasher.params
+	return pi.Algorithm != hasher.algo ||
+		pi.Others.(*Argon2Params).weakerThan(hasher.params) ||
+		formatOf(encoded) != hasher.format
 }
 
 func (hasher *argon2Hasher) Harden(password, encoded string) (string, error) {
-	// TODO(zhaowentao): 处理 Verify
-	return encoded, nil
+	return harden(hasher, password, encoded)
 }
 
 func newArgon2Hasher(opt *HasherOption) (Hasher, error) {
-	var params *Argon2Params
-	if opt.Params == nil {
-		params = defaultArgon2Params
-	} else {
-		p, ok := opt.Params.(*Argon2Params)
-		if !ok {
-			params = defaultArgon2Params
-		} else {
-			params = p
-		}
+	if opt.Format == FormatWerkzeug || opt.Format == FormatPasslib {
+		return nil, ErrUnsupportedFormat
+	}
+	defaults := defaultArgon2Params
+	if opt.Format == FormatDjango {
+		defaults = djangoArgon2Params
 	}
-	return &argon2Hasher{params: params}, nil
+	params, err := parseArgon2Params(opt.Params, defaults)
+	if err != nil {
+		return nil, err
+	}
+	return &argon2Hasher{algo: opt.Algorithm, format: opt.Format, params: params}, nil
+}
+
+func init() {
+	mustRegisterHasher(argon2Algo, newArgon2Hasher)
+	mustRegisterHasher(argon2iAlgo, newArgon2Hasher)
+	mustRegisterHasher(argon2dAlgo, newArgon2Hasher)
 }
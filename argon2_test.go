@@ -1,6 +1,8 @@
 package password
 
 import (
+	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -23,8 +25,8 @@ func TestDefaultArgon2(t *testing.T) {
 
 	wrongEncoded := "aa" + encoded1
 	_, err = hasher.Decode(wrongEncoded)
-	if err != errUnknownAlgorithm {
-		t.Errorf("Decode(wrongEncoded) should be errUnknownAlgorithm: %s", err)
+	if !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Decode(wrongEncoded) should be ErrUnknownAlgorithm: %s", err)
 	}
 
 	parts := strings.SplitN(encoded1, sep, 7)
@@ -98,11 +100,11 @@ func TestArgon2(t *testing.T) {
 		Iterations: 1,
 		Algorithm:  argon2Algo,
 		Params: &Argon2Params{
-			memory:      32 * 1024,
-			iterations:  10,
-			parallelism: 2,
-			saltLength:  8,
-			keyLength:   32,
+			Memory:      32 * 1024,
+			Iterations:  10,
+			Parallelism: 2,
+			SaltLength:  8,
+			KeyLength:   32,
 		},
 	}
 	hasher, err := NewHasher(opt)
@@ -144,11 +146,11 @@ func TestMustUpdateForArgon2(t *testing.T) {
 		Iterations: 1,
 		Algorithm:  argon2Algo,
 		Params: &Argon2Params{
-			memory:      32 * 1024,
-			iterations:  10,
-			parallelism: 2,
-			saltLength:  8,
-			keyLength:   32,
+			Memory:      32 * 1024,
+			Iterations:  10,
+			Parallelism: 2,
+			SaltLength:  8,
+			KeyLength:   32,
 		},
 	}
 	hasher, _ := NewHasher(opt)
@@ -162,15 +164,187 @@ func TestMustUpdateForArgon2(t *testing.T) {
 		Iterations: 1,
 		Algorithm:  argon2Algo,
 		Params: &Argon2Params{
-			memory:      32 * 1024,
-			iterations:  10,
-			parallelism: 2,
-			saltLength:  9,
-			keyLength:   32,
+			Memory:      32 * 1024,
+			Iterations:  10,
+			Parallelism: 2,
+			SaltLength:  9,
+			KeyLength:   32,
 		},
 	}
 	hasher, _ = NewHasher(opt2)
 	if !hasher.MustUpdate(encoded) {
-		t.Error("should updated because of different param")
+		t.Error("should updated because of longer salt")
+	}
+
+	// a stronger hash is kept rather than downgraded
+	weak, _ := NewHasher(&HasherOption{
+		Iterations: 1,
+		Algorithm:  argon2Algo,
+		Params: &Argon2Params{
+			Memory:      16 * 1024,
+			Iterations:  2,
+			Parallelism: 4,
+			SaltLength:  8,
+			KeyLength:   32,
+		},
+	})
+	if weak.MustUpdate(encoded) {
+		t.Error("should not update a stronger hash")
+	}
+	hardened, err := weak.Harden(password, encoded)
+	if err != nil || hardened != encoded {
+		t.Errorf("Harden() should return the stronger hash unchanged: %s %s", hardened, err)
+	}
+}
+
+func TestArgon2ParamsFromJSON(t *testing.T) {
+	config := []byte(`{
+		"algorithm": "argon2id",
+		"iterations": 1,
+		"params": {"memory": 32768, "iterations": 2, "parallelism": 2}
+	}`)
+	var opt HasherOption
+	if err := json.Unmarshal(config, &opt); err != nil {
+		t.Fatalf("json.Unmarshal should be ok: %s", err)
+	}
+
+	hasher, err := NewHasher(&opt)
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+	encoded, _ := hasher.Encode(password)
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode(encoded) should be ok: %s", err)
+	}
+
+	expected := Argon2Params{
+		Memory:      32768,
+		Iterations:  2,
+		Parallelism: 2,
+		SaltLength:  defaultArgon2Params.SaltLength,
+		KeyLength:   defaultArgon2Params.KeyLength,
+	}
+	if p := pi.Others.(*Argon2Params); *p != expected {
+		t.Errorf("Params should be %+v: %+v", expected, *p)
+	}
+	if hasher.MustUpdate(encoded) {
+		t.Error("should not update")
+	}
+}
+
+func TestIllegalArgon2Params(t *testing.T) {
+	data := []struct {
+		name   string
+		params interface{}
+	}{
+		{
+			name:   "zero iterations",
+			params: map[string]interface{}{"iterations": 0},
+		},
+		{
+			name:   "zero parallelism",
+			params: &Argon2Params{Memory: 1024, Iterations: 1, SaltLength: 16, KeyLength: 32},
+		},
+		{
+			name:   "too little memory",
+			params: map[string]interface{}{"memory": 16, "parallelism": 4},
+		},
+		{
+			name:   "short salt",
+			params: map[string]interface{}{"salt_length": 4},
+		},
+		{
+			name:   "short key",
+			params: Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 8},
+		},
+		{
+			name:   "wrong type",
+			params: map[string]interface{}{"memory": "a lot"},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			opt := &HasherOption{Algorithm: argon2Algo, Iterations: 1, Params: d.params}
+			if _, err := NewHasher(opt); err == nil {
+				t.Error("NewHasher should be error")
+			}
+		})
+	}
+}
+
+func TestArgon2Variants(t *testing.T) {
+	params := &Argon2Params{
+		Memory:      1024,
+		Iterations:  2,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	idHasher, _ := NewHasher(&HasherOption{Algorithm: argon2Algo, Iterations: 1, Params: params})
+
+	for _, algo := range []string{argon2iAlgo, argon2dAlgo} {
+		t.Run(algo, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1, Params: params})
+			if err != nil {
+				t.Fatalf("NewHasher(%s) should be ok: %s", algo, err)
+			}
+
+			encoded, err := hasher.Encode(password)
+			if err != nil {
+				t.Fatalf("Encode(password) should be ok: %s", err)
+			}
+			if !strings.HasPrefix(encoded, algo+sep) {
+				t.Errorf("encoded should start with %s: %s", algo, encoded)
+			}
+
+			pi, err := hasher.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode(encoded) should be ok: %s", err)
+			}
+			if pi.Algorithm != algo {
+				t.Errorf("Algorithm should be %s: %s", algo, pi.Algorithm)
+			}
+
+			if !hasher.Verify(password, encoded) {
+				t.Error("Verify() should be true")
+			}
+			if hasher.Verify("wrong", encoded) {
+				t.Error("Verify(wrong) should be false")
+			}
+			if hasher.MustUpdate(encoded) {
+				t.Error("should not update")
+			}
+
+			// argon2id hasher verifies other variants and upgrades them
+			if !idHasher.Verify(password, encoded) {
+				t.Error("argon2id hasher should verify")
+			}
+			if !idHasher.MustUpdate(encoded) {
+				t.Error("should update to argon2id")
+			}
+			if !Verify(password, encoded) {
+				t.Error("default manager should verify")
+			}
+		})
+	}
+}
+
+func TestBareArgon2(t *testing.T) {
+	bare := "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+	params := &Argon2Params{Memory: 65536, Iterations: 2, Parallelism: 4, SaltLength: 8, KeyLength: 24}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: argon2iAlgo, Iterations: 1, Params: params})
+	if !hasher.Verify("password", bare) {
+		t.Error("Verify(bare) should be true")
+	}
+	if !hasher.MustUpdate(bare) {
+		t.Error("should update bare argon2 to the default format")
+	}
+
+	phcHasher, _ := NewHasher(&HasherOption{Algorithm: argon2iAlgo, Iterations: 1, Params: params, Format: FormatPHC})
+	if phcHasher.MustUpdate(bare) {
+		t.Error("should not update because of same format")
 	}
 }
@@ -0,0 +1,221 @@
+package password
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/blake2b"
+)
+
+// golang.org/x/crypto/argon2 only exports argon2i and argon2id, so argon2d,
+// which uses data-dependent addressing only, is implemented here following
+// RFC 9106. Lanes are processed one by one, which gives the same result as
+// processing them in parallel.
+
+const (
+	argon2dMode        = 0
+	argon2BlockLength  = 128
+	argon2SyncPoints   = 4
+	argon2BlockBytes   = argon2BlockLength * 8
+	argon2PrehashBytes = blake2b.Size + 8
+)
+
+type argon2Block [argon2BlockLength]uint64
+
+// argon2dKey like `argon2.IDKey`, derives a key with argon2d.
+func argon2dKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	return argon2dDeriveKey(password, salt, nil, nil, time, memory, threads, keyLen)
+}
+
+func argon2dDeriveKey(password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	lanes := uint32(threads)
+	h0 := argon2InitHash(password, salt, secret, data, time, memory, lanes, keyLen, argon2dMode)
+
+	memory = memory / (argon2SyncPoints * lanes) * (argon2SyncPoints * lanes)
+	if memory < 2*argon2SyncPoints*lanes {
+		memory = 2 * argon2SyncPoints * lanes
+	}
+	laneLength := memory / lanes
+	segmentLength := laneLength / argon2SyncPoints
+
+	B := make([]argon2Block, memory)
+	var buf [argon2BlockBytes]byte
+	for lane := uint32(0); lane < lanes; lane++ {
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], i)
+			argon2Blake2bLong(buf[:], h0[:])
+			b := &B[lane*laneLength+i]
+			for j := range b {
+				b[j] = binary.LittleEndian.Uint64(buf[j*8:])
+			}
+		}
+	}
+
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			for lane := uint32(0); lane < lanes; lane++ {
+				index := uint32(0)
+				if n == 0 && slice == 0 {
+					index = 2
+				}
+				offset := lane*laneLength + slice*segmentLength + index
+				for ; index < segmentLength; index, offset = index+1, offset+1 {
+					prev := offset - 1
+					if index == 0 && slice == 0 {
+						prev += laneLength
+					}
+					random := B[prev][0]
+					ref := argon2IndexAlpha(random, laneLength, segmentLength, lanes, n, slice, lane, index)
+					argon2Compress(&B[offset], &B[prev], &B[ref], n > 0)
+				}
+			}
+		}
+	}
+
+	last := &B[memory-1]
+	for lane := uint32(0); lane < lanes-1; lane++ {
+		for i, v := range B[lane*laneLength+laneLength-1] {
+			last[i] ^= v
+		}
+	}
+	for i, v := range last {
+		binary.LittleEndian.PutUint64(buf[i*8:], v)
+	}
+	key := make([]byte, keyLen)
+	argon2Blake2bLong(key, buf[:])
+	return key
+}
+
+func argon2InitHash(password, salt, secret, data []byte, time, memory, threads, keyLen uint32, mode int) [argon2PrehashBytes]byte {
+	var h0 [argon2PrehashBytes]byte
+	var tmp [4]byte
+	b2, _ := blake2b.New512(nil)
+	for _, v := range []uint32{threads, keyLen, memory, time, argon2.Version, uint32(mode)} {
+		binary.LittleEndian.PutUint32(tmp[:], v)
+		b2.Write(tmp[:])
+	}
+	for _, v := range [][]byte{password, salt, secret, data} {
+		binary.LittleEndian.PutUint32(tmp[:], uint32(len(v)))
+		b2.Write(tmp[:])
+		b2.Write(v)
+	}
+	b2.Sum(h0[:0])
+	return h0
+}
+
+// argon2Blake2bLong is the variable-length hash function H' of argon2.
+func argon2Blake2bLong(out, in []byte) {
+	var b2 hash.Hash
+	if n := len(out); n < blake2b.Size {
+		b2, _ = blake2b.New(n, nil)
+	} else {
+		b2, _ = blake2b.New512(nil)
+	}
+
+	var v [blake2b.Size]byte
+	binary.LittleEndian.PutUint32(v[:4], uint32(len(out)))
+	b2.Write(v[:4])
+	b2.Write(in)
+	if len(out) <= blake2b.Size {
+		b2.Sum(out[:0])
+		return
+	}
+
+	r := (len(out)+31)/32 - 2
+	b2.Sum(v[:0])
+	for i := 0; i < r; i++ {
+		copy(out[i*32:], v[:32])
+		if i < r-1 {
+			b2, _ = blake2b.New512(nil)
+		} else {
+			b2, _ = blake2b.New(len(out)-32*r, nil)
+		}
+		b2.Write(v[:])
+		b2.Sum(v[:0])
+	}
+	copy(out[32*r:], v[:len(out)-32*r])
+}
+
+// argon2Compress is the compression function G. The result is xored into
+// out if xor, as required by version 0x13 for passes after the first one.
+func argon2Compress(out, in1, in2 *argon2Block, xor bool) {
+	var t argon2Block
+	for i := range t {
+		t[i] = in1[i] ^ in2[i]
+	}
+	r := t
+	for i := 0; i < argon2BlockLength; i += 16 {
+		argon2Blamka(&t, i, i+1, i+2, i+3, i+4, i+5, i+6, i+7,
+			i+8, i+9, i+10, i+11, i+12, i+13, i+14, i+15)
+	}
+	for i := 0; i < argon2BlockLength/8; i += 2 {
+		argon2Blamka(&t, i, i+1, 16+i, 16+i+1, 32+i, 32+i+1, 48+i, 48+i+1,
+			64+i, 64+i+1, 80+i, 80+i+1, 96+i, 96+i+1, 112+i, 112+i+1)
+	}
+	for i := range t {
+		if xor {
+			out[i] ^= r[i] ^ t[i]
+		} else {
+			out[i] = r[i] ^ t[i]
+		}
+	}
+}
+
+// argon2Blamka applies the permutation P on the 16 words of t at the given indexes.
+func argon2Blamka(t *argon2Block, i00, i01, i02, i03, i04, i05, i06, i07, i08, i09, i10, i11, i12, i13, i14, i15 int) {
+	argon2GB(t, i00, i04, i08, i12)
+	argon2GB(t, i01, i05, i09, i13)
+	argon2GB(t, i02, i06, i10, i14)
+	argon2GB(t, i03, i07, i11, i15)
+	argon2GB(t, i00, i05, i10, i15)
+	argon2GB(t, i01, i06, i11, i12)
+	argon2GB(t, i02, i07, i08, i13)
+	argon2GB(t, i03, i04, i09, i14)
+}
+
+func argon2GB(t *argon2Block, a, b, c, d int) {
+	fBlaMka := func(x, y uint64) uint64 {
+		return x + y + 2*uint64(uint32(x))*uint64(uint32(y))
+	}
+	t[a] = fBlaMka(t[a], t[b])
+	t[d] = rotr64(t[d]^t[a], 32)
+	t[c] = fBlaMka(t[c], t[d])
+	t[b] = rotr64(t[b]^t[c], 24)
+	t[a] = fBlaMka(t[a], t[b])
+	t[d] = rotr64(t[d]^t[a], 16)
+	t[c] = fBlaMka(t[c], t[d])
+	t[b] = rotr64(t[b]^t[c], 63)
+}
+
+func rotr64(x uint64, n uint) uint64 {
+	return x>>n | x<<(64-n)
+}
+
+// argon2IndexAlpha maps the pseudo-random value to the index of the reference block.
+func argon2IndexAlpha(random uint64, laneLength, segmentLength, lanes, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(random>>32) % lanes
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+
+	area, start := 3*segmentLength, ((slice+1)%argon2SyncPoints)*segmentLength
+	if lane == refLane {
+		area += index
+	}
+	if n == 0 {
+		area, start = slice*segmentLength, 0
+		if slice == 0 || lane == refLane {
+			area += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		area--
+	}
+
+	p := random & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * uint64(area)) >> 32
+	return refLane*laneLength + uint32((uint64(start)+uint64(area)-(p+1))%uint64(laneLength))
+}
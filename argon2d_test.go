@@ -0,0 +1,20 @@
+package password
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestArgon2dRFCVector(t *testing.T) {
+	password := bytes.Repeat([]byte{0x01}, 32)
+	salt := bytes.Repeat([]byte{0x02}, 16)
+	secret := bytes.Repeat([]byte{0x03}, 8)
+	data := bytes.Repeat([]byte{0x04}, 12)
+
+	tag := argon2dDeriveKey(password, salt, secret, data, 3, 32, 4, 32)
+	expected := "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"
+	if hex.EncodeToString(tag) != expected {
+		t.Errorf("argon2d tag should be %s: %x", expected, tag)
+	}
+}
@@ -0,0 +1,192 @@
+package password
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// aspNetIdentityAlgo verifies passwords of ASP.NET Core Identity, which are
+// base64 of:
+//
+//	V2: 0x00 | salt (16 bytes) | subkey (32 bytes), PBKDF2-HMAC-SHA1 of 1000 iterations
+//	V3: 0x01 | prf (uint32) | iterations (uint32) | salt length (uint32) | salt | subkey
+//
+// Integers are big-endian, prf is 0 for HMAC-SHA1, 1 for HMAC-SHA256 and 2
+// for HMAC-SHA512.
+const aspNetIdentityAlgo = "aspnet_identity"
+
+const (
+	aspNetIdentityV2 = 0x00
+	aspNetIdentityV3 = 0x01
+
+	aspNetIdentityV2Iterations   = 1000
+	aspNetIdentityV2SaltLength   = 16
+	aspNetIdentityV2SubkeyLength = 32
+	aspNetIdentityV3HeaderLength = 13
+
+	// minAspNetIdentityLength of salt and subkey of V3 in bytes
+	minAspNetIdentityLength = 16
+
+	aspNetIdentitySaltLength   = 16
+	aspNetIdentitySubkeyLength = 32
+
+	// defaultAspNetIdentityIterations is the default of V3 since .NET 7
+	defaultAspNetIdentityIterations = 100000
+	maxAspNetIdentityIterations     = 1<<31 - 1
+)
+
+// aspNetIdentityPRFs maps PRF ids of V3 to pbkdf2 algorithms.
+var aspNetIdentityPRFs = map[uint32]string{
+	0: pbkdf2Sha1Algo,
+	1: pbkdf2Sha256Algo,
+	2: pbkdf2Sha512Algo,
+}
+
+// AspNetIdentityInfo is Others of PasswordInfo decoded from ASP.NET Core
+// Identity passwords.
+type AspNetIdentityInfo struct {
+	// Version: 2 or 3
+	Version int
+	// PRF: pbkdf2 algorithm of the PRF, such as pbkdf2_sha256
+	PRF string
+}
+
+type aspNetIdentityHasher struct {
+	iterCount int
+}
+
+// Encode encodes password in V3 with HMAC-SHA512, which is the default of
+// ASP.NET Core Identity since .NET 7, so that .NET applications can verify it.
+func (hasher *aspNetIdentityHasher) Encode(password string) (string, error) {
+	salt, err := generateRandomBytes(aspNetIdentitySaltLength)
+	if err != nil {
+		return "", err
+	}
+	_, newFunc := pbkdf2SizeAndNew(pbkdf2Sha512Algo)
+	subkey := pbkdf2.Key([]byte(password), salt, hasher.iterCount, aspNetIdentitySubkeyLength, newFunc)
+
+	b := make([]byte, aspNetIdentityV3HeaderLength, aspNetIdentityV3HeaderLength+len(salt)+len(subkey))
+	b[0] = aspNetIdentityV3
+	binary.BigEndian.PutUint32(b[1:], 2)
+	binary.BigEndian.PutUint32(b[5:], uint32(hasher.iterCount))
+	binary.BigEndian.PutUint32(b[9:], uint32(len(salt)))
+	b = append(append(b, salt...), subkey...)
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Decode decodes V2 and V3 passwords, Others of PasswordInfo is
+// *AspNetIdentityInfo, and Hash is base64 of the subkey.
+func (hasher *aspNetIdentityHasher) Decode(encoded string) (*PasswordInfo, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(b) == 0 {
+		return nil, errUnknownAlgorithmOf(strings.SplitN(encoded, sep, 2)[0])
+	}
+
+	switch b[0] {
+	case aspNetIdentityV2:
+		if len(b) != 1+aspNetIdentityV2SaltLength+aspNetIdentityV2SubkeyLength {
+			return nil, ErrMalformedEncoded
+		}
+		return newAspNetIdentityPasswordInfo(2, pbkdf2Sha1Algo, aspNetIdentityV2Iterations,
+			b[1:1+aspNetIdentityV2SaltLength], b[1+aspNetIdentityV2SaltLength:]), nil
+	case aspNetIdentityV3:
+		if len(b) < aspNetIdentityV3HeaderLength {
+			return nil, ErrMalformedEncoded
+		}
+		prf, ok := aspNetIdentityPRFs[binary.BigEndian.Uint32(b[1:])]
+		if !ok {
+			return nil, errIllegalParam("prf", "should be 0, 1 or 2")
+		}
+		iter := binary.BigEndian.Uint32(b[5:])
+		if iter < 1 || iter > maxAspNetIdentityIterations {
+			return nil, errIllegalParam("iterations", "should be at least 1")
+		}
+		saltLength := binary.BigEndian.Uint32(b[9:])
+		if saltLength < minAspNetIdentityLength ||
+			uint64(saltLength)+minAspNetIdentityLength > uint64(len(b)-aspNetIdentityV3HeaderLength) {
+			return nil, errMalformed("salt", nil)
+		}
+		salt := b[aspNetIdentityV3HeaderLength : aspNetIdentityV3HeaderLength+saltLength]
+		return newAspNetIdentityPasswordInfo(3, prf, int(iter), salt, b[aspNetIdentityV3HeaderLength+saltLength:]), nil
+	}
+	return nil, errUnknownAlgorithmOf(strings.SplitN(encoded, sep, 2)[0])
+}
+
+func newAspNetIdentityPasswordInfo(version int, prf string, iter int, salt, subkey []byte) *PasswordInfo {
+	return &PasswordInfo{
+		Algorithm:  aspNetIdentityAlgo,
+		Iterations: iter,
+		Salt:       string(salt),
+		Hash:       base64.StdEncoding.EncodeToString(subkey),
+		Others:     &AspNetIdentityInfo{Version: version, PRF: prf},
+	}
+}
+
+func (hasher *aspNetIdentityHasher) Verify(password, encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+
+	subkey, err := base64.StdEncoding.DecodeString(pi.Hash)
+	if err != nil {
+		return false
+	}
+	_, newFunc := pbkdf2SizeAndNew(pi.Others.(*AspNetIdentityInfo).PRF)
+	key := pbkdf2.Key([]byte(password), []byte(pi.Salt), pi.Iterations, len(subkey), newFunc)
+	return subtle.ConstantTimeCompare(key, subkey) == 1
+}
+
+// MustUpdate returns true if encoded is not V3 with HMAC-SHA512, or its
+// iterations or salt are less than the configured ones. PasswordManager
+// migrates them anyway if another algorithm is preferred.
+func (hasher *aspNetIdentityHasher) MustUpdate(encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+	info := pi.Others.(*AspNetIdentityInfo)
+	return info.Version != 3 || info.PRF != pbkdf2Sha512Algo || pi.Iterations < hasher.iterCount ||
+		len(pi.Salt) < aspNetIdentitySaltLength
+}
+
+func (hasher *aspNetIdentityHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+// isAspNetIdentity reports whether encoded is an ASP.NET Core Identity password.
+// The length and the format marker, 'A' in base64 for both versions, are
+// checked before decoding.
+func isAspNetIdentity(encoded string) bool {
+	minLength := base64.StdEncoding.EncodedLen(aspNetIdentityV3HeaderLength + 2*minAspNetIdentityLength)
+	if len(encoded) < minLength || len(encoded)%4 != 0 || encoded[0] != 'A' {
+		return false
+	}
+	_, err := (&aspNetIdentityHasher{}).Decode(encoded)
+	return err == nil
+}
+
+// newAspNetIdentityHasher makes an aspnet_identity hasher, iterations are
+// Iterations of opt, at least `defaultAspNetIdentityIterations`.
+func newAspNetIdentityHasher(opt *HasherOption) (Hasher, error) {
+	if opt.Format != FormatDefault {
+		return nil, ErrUnsupportedFormat
+	}
+	if opt.Iterations > maxAspNetIdentityIterations {
+		return nil, ErrIllegalIterations
+	}
+
+	iterCount := defaultAspNetIdentityIterations
+	if opt.Iterations > iterCount {
+		iterCount = opt.Iterations
+	}
+	return &aspNetIdentityHasher{iterCount: iterCount}, nil
+}
+
+func init() {
+	mustRegisterHasher(aspNetIdentityAlgo, newAspNetIdentityHasher)
+}
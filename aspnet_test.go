@@ -0,0 +1,110 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// generated like `PasswordHasher<TUser>.HashPassword` of "password"
+var aspNetIdentityPasswords = []struct {
+	version    int
+	prf        string
+	iterations int
+	encoded    string
+}{
+	{2, pbkdf2Sha1Algo, 1000, "AAECAwQFBgcICQoLDA0ODxDq+5ElXuaAQFFbEfqJgqscp+0EOLJqKblY+cwudxUvNQ=="},
+	{3, pbkdf2Sha256Algo, 10000, "AQAAAAEAACcQAAAAEAECAwQFBgcICQoLDA0ODxAm1Puif67kXEI/oQUWilAi0nwEAQh/2jME7t3q/2Jq6g=="},
+	{3, pbkdf2Sha512Algo, 100000, "AQAAAAIAAYagAAAAEAECAwQFBgcICQoLDA0ODxDQkiNvCwqskcX0B0gwy3Esxu+17t6x4HQ+pC5AGL7AHA=="},
+}
+
+func TestAspNetIdentity(t *testing.T) {
+	hasher, err := NewHasher(&HasherOption{Algorithm: aspNetIdentityAlgo, Iterations: 1000})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, d := range aspNetIdentityPasswords {
+		pi, err := hasher.Decode(d.encoded)
+		if err != nil {
+			t.Fatalf("Decode(%s) should be ok: %s", d.encoded, err)
+		}
+		info := pi.Others.(*AspNetIdentityInfo)
+		if pi.Algorithm != aspNetIdentityAlgo || pi.Iterations != d.iterations || len(pi.Salt) != 16 ||
+			info.Version != d.version || info.PRF != d.prf {
+			t.Errorf("Decode(%s) should be V%d of %s: %+v %+v", d.encoded, d.version, d.prf, pi, info)
+		}
+		if !hasher.Verify("password", d.encoded) {
+			t.Errorf("Verify(%s) should be true", d.encoded)
+		}
+		if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+			t.Errorf("Check(wrong) should be ErrMismatch: %s", err)
+		}
+		// V2, HMAC-SHA256, or less iterations
+		if hasher.MustUpdate(d.encoded) != (d.prf != pbkdf2Sha512Algo) {
+			t.Errorf("MustUpdate(%s) should be %v", d.encoded, d.prf != pbkdf2Sha512Algo)
+		}
+
+		if algo, err := Identify(d.encoded); err != nil || algo != aspNetIdentityAlgo {
+			t.Errorf("Identify(%s) should be %s: %s %s", d.encoded, aspNetIdentityAlgo, algo, err)
+		}
+		if !Verify("password", d.encoded) {
+			t.Errorf("default PasswordManager should verify %s", d.encoded)
+		}
+	}
+
+	encoded, _ := hasher.Encode(password)
+	pi, _ := hasher.Decode(encoded)
+	// at least 100000 iterations
+	if info := pi.Others.(*AspNetIdentityInfo); info.Version != 3 || info.PRF != pbkdf2Sha512Algo || pi.Iterations != 100000 {
+		t.Errorf("Encode() should be V3 with HMAC-SHA512: %+v %d", info, pi.Iterations)
+	}
+	if !hasher.Verify(password, encoded) || hasher.MustUpdate(encoded) {
+		t.Error("Verify() should be true without update")
+	}
+	stronger, _ := NewHasher(&HasherOption{Algorithm: aspNetIdentityAlgo, Iterations: 200000})
+	if !stronger.MustUpdate(encoded) {
+		t.Error("should update less iterations")
+	}
+
+	if _, err := NewHasher(&HasherOption{Algorithm: aspNetIdentityAlgo, Iterations: 1, Format: FormatPHC}); err != ErrUnsupportedFormat {
+		t.Errorf("aspnet_identity should not support PHC format: %s", err)
+	}
+}
+
+func TestIllegalAspNetIdentity(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: aspNetIdentityAlgo, Iterations: 1})
+	v3, _ := base64.StdEncoding.DecodeString(aspNetIdentityPasswords[1].encoded)
+	header := func(prf, iter, saltLength byte) string {
+		b := append([]byte{}, v3...)
+		b[4], b[8], b[12] = prf, iter, saltLength
+		b[6], b[7] = 0, 0
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"md5$salt$hash", ErrUnknownAlgorithm},
+		{base64.StdEncoding.EncodeToString([]byte{0x02, 1, 2, 3}), ErrUnknownAlgorithm},
+		{base64.StdEncoding.EncodeToString(make([]byte, 48)), ErrMalformedEncoded},
+		{base64.StdEncoding.EncodeToString(v3[:12]), ErrMalformedEncoded},
+		{header(3, 1, 16), ErrIllegalParam},
+		{header(1, 0, 16), ErrIllegalParam},
+		{header(1, 1, 8), ErrMalformedEncoded},
+		{header(1, 1, 40), ErrMalformedEncoded},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+
+	// short, or without the format marker
+	for _, encoded := range []string{"AAAA", "B" + aspNetIdentityPasswords[0].encoded[1:]} {
+		if isAspNetIdentity(encoded) {
+			t.Errorf("%s should not be ASP.NET Core Identity password", encoded)
+		}
+	}
+}
@@ -2,11 +2,23 @@ package password
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// bcryptIdentifiers maps identifiers of bare modular crypt bcrypt strings,
+// such as `$2y$10$...` made by PHP's password_hash, to algorithm.
+var bcryptIdentifiers = map[string]string{
+	"2a": bcryptAlgo,
+	"2b": bcryptAlgo,
+	"2y": bcryptAlgo,
+}
+
+// bcryptHashLength is the length of `$2a$<cost>$<salt><hash>`.
+const bcryptHashLength = 60
+
 type bcryptHasher struct {
 	algo string
 	cost int
@@ -16,11 +28,26 @@ func (hasher *bcryptHasher) Encode(password string) (string, error) {
 	return hasher.encode(password, hasher.algo, hasher.cost)
 }
 
+// sha256Hex returns hex SHA-256 digest of password, which bcrypt_sha256 hashes
+// like Django. The hex digest has no NUL bytes and fits in 72 bytes of bcrypt.
+func sha256Hex(password string) []byte {
+	d := sha256.Sum256([]byte(password))
+	return []byte(hex.EncodeToString(d[:]))
+}
+
+// sha256Raw returns raw SHA-256 digest of password, which bcrypt_sha256 of
+// earlier versions of this package hashed. Django and passlib hash the hex
+// digest instead, so passwords hashed this way are only verified, and must
+// be updated.
+func sha256Raw(password string) []byte {
+	d := sha256.Sum256([]byte(password))
+	return d[:]
+}
+
 func (hasher *bcryptHasher) encode(password, algo string, cost int) (string, error) {
 	var data []byte
 	if algo == bcryptSha256Algo {
-		d := sha256.Sum256([]byte(password))
-		data = d[:]
+		data = sha256Hex(password)
 	} else {
 		data = []byte(password)
 	}
@@ -38,14 +65,24 @@ func (hasher *bcryptHasher) encode(password, algo string, cost int) (string, err
 }
 
 func (hasher *bcryptHasher) Decode(decoded string) (*PasswordInfo, error) {
+	if isBareBcrypt(decoded) {
+		decoded = bcryptAlgo + sep + decoded
+	}
+
 	parts := strings.SplitN(decoded, sep, 2)
 	if parts[0] != bcryptSha256Algo && parts[0] != bcryptAlgo {
-		return nil, errUnknownAlgorithm
+		return nil, errUnknownAlgorithmOf(parts[0])
+	}
+	if len(parts) != 2 {
+		return nil, ErrMalformedEncoded
 	}
 
 	cost, err := bcrypt.Cost([]byte(parts[1]))
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("hash", err)
+	}
+	if len(parts[1]) != bcryptHashLength {
+		return nil, errMalformed("hash", nil)
 	}
 
 	return &PasswordInfo{
@@ -56,36 +93,57 @@ func (hasher *bcryptHasher) Decode(decoded string) (*PasswordInfo, error) {
 }
 
 func (hasher *bcryptHasher) Verify(password, encoded string) bool {
+	ok, _ := hasher.verifyUpgrade(password, encoded)
+	return ok
+}
+
+// verifyUpgrade verifies bcrypt_sha256 passwords hashed over the raw digest
+// as well, they must be updated, but can't be told without password.
+func (hasher *bcryptHasher) verifyUpgrade(password, encoded string) (bool, bool) {
 	pi, err := hasher.Decode(encoded)
 	if err != nil {
-		return false
+		return false, false
 	}
 
-	var data []byte
-	if pi.Algorithm == bcryptSha256Algo {
-		d := sha256.Sum256([]byte(password))
-		data = d[:]
-	} else {
-		data = []byte(password)
+	if pi.Algorithm != bcryptSha256Algo {
+		return bcrypt.CompareHashAndPassword([]byte(pi.Hash), []byte(password)) == nil, hasher.MustUpdate(encoded)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(pi.Hash), data)
-	return err == nil
+	if bcrypt.CompareHashAndPassword([]byte(pi.Hash), sha256Hex(password)) == nil {
+		return true, hasher.MustUpdate(encoded)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(pi.Hash), sha256Raw(password)) == nil, true
 }
 
+// MustUpdate returns true if algorithm or cost differs from the configured one,
+// or encoded is a bare bcrypt string without algorithm prefix.
 func (hasher *bcryptHasher) MustUpdate(encoded string) bool {
 	pi, err := hasher.Decode(encoded)
 	if err != nil {
 		return false
 	}
-	return pi.Iterations < hasher.cost
+	return pi.Algorithm != hasher.algo || pi.Iterations < hasher.cost || isBareBcrypt(encoded)
 }
 
 func (hasher *bcryptHasher) Harden(password, encoded string) (string, error) {
-	// TODO(zhaowentao)
-	return encoded, nil
+	return harden(hasher, password, encoded)
+}
+
+// isBareBcrypt reports whether encoded is a modular crypt bcrypt string
+// like `$2y$10$...`, without algorithm prefix.
+func isBareBcrypt(encoded string) bool {
+	if !strings.HasPrefix(encoded, sep) {
+		return false
+	}
+	id := strings.SplitN(encoded[len(sep):], sep, 2)[0]
+	_, ok := bcryptIdentifiers[id]
+	return ok
 }
 
 func newBcryptHasher(opt *HasherOption) (Hasher, error) {
+	if defaultFormatOf(opt.Format) != FormatDefault {
+		return nil, ErrUnsupportedFormat
+	}
+
 	cost := bcrypt.DefaultCost
 	if opt.Iterations > cost {
 		cost = opt.Iterations
@@ -96,3 +154,8 @@ func newBcryptHasher(opt *HasherOption) (Hasher, error) {
 		cost: cost,
 	}, nil
 }
+
+func init() {
+	mustRegisterHasher(bcryptAlgo, newBcryptHasher)
+	mustRegisterHasher(bcryptSha256Algo, newBcryptHasher)
+}
@@ -6,7 +6,10 @@ Prefix Cost   Salt                   Hashed Text
 */
 package password
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestBcryptSha256(t *testing.T) {
 	opt := &HasherOption{
@@ -114,3 +117,44 @@ func TestMustUpdateForBcrypt(t *testing.T) {
 		t.Error("should not update because of smaller cost")
 	}
 }
+
+func TestBareBcrypt(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: bcryptAlgo, Iterations: 10})
+	encoded, _ := hasher.Encode(password)
+	pi, _ := hasher.Decode(encoded)
+
+	for _, id := range []string{"2a", "2b", "2y"} {
+		bare := "$" + id + pi.Hash[3:]
+		t.Run(id, func(t *testing.T) {
+			algo, err := Identify(bare)
+			if err != nil || algo != bcryptAlgo {
+				t.Errorf("Identify(%s) should be bcrypt: %s %s", bare, algo, err)
+			}
+
+			pi, err := hasher.Decode(bare)
+			if err != nil {
+				t.Fatalf("Decode(bare) should be ok: %s", err)
+			}
+			if pi.Algorithm != bcryptAlgo || pi.Hash != bare || pi.Iterations != 10 {
+				t.Errorf("wrong PasswordInfo: %+v", pi)
+			}
+
+			if !hasher.Verify(password, bare) || !Verify(password, bare) {
+				t.Error("Verify(bare) should be true")
+			}
+			if hasher.Verify("wrong", bare) {
+				t.Error("Verify(wrong, bare) should be false")
+			}
+			if !hasher.MustUpdate(bare) {
+				t.Error("should update bare bcrypt")
+			}
+		})
+	}
+
+	if hasher.MustUpdate(encoded) {
+		t.Error("should not update")
+	}
+	if _, err := hasher.Decode("$2x$04$abc"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Decode($2x$) should be ErrUnknownAlgorithm: %s", err)
+	}
+}
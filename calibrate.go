@@ -0,0 +1,216 @@
+package password
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Security floors of calibration, see OWASP Password Storage Cheat Sheet.
+const (
+	minPbkdf2Sha256Iterations = 600000
+	minPbkdf2Sha1Iterations   = 1300000
+	minPbkdf2Sha512Iterations = 210000
+	minBcryptCost             = bcrypt.DefaultCost
+	// argon2 memory in KiB, at least 2 iterations below minArgon2MemoryOneIteration
+	minArgon2Memory              = 19 * 1024
+	minArgon2MemoryOneIteration  = 46 * 1024
+	minScryptN                   = 1 << 14
+	scryptCalibrationBlockLength = 8
+)
+
+// ErrCalibrationUnsupported algorithm has no cost to calibrate.
+var ErrCalibrationUnsupported = newError(CodeCalibrationUnsupported, "algorithm cannot be calibrated")
+
+// calibrationRounds is the number of measurements, the fastest one is used.
+const calibrationRounds = 3
+
+const (
+	calibrationPassword = "calibration password"
+	calibrationSalt     = "calibration salt"
+)
+
+// Calibrate benchmarks algorithm on the current host, and returns a
+// HasherOption whose single hash takes about target.
+//
+// memoryCeiling is the maximum memory in bytes of a hash for argon2 and
+// scrypt, the default params are used if 0, it is ignored by other algorithms.
+// The result never drops below the recommended minimums, so a hash may take
+// longer than target on slow hosts. pbkdf2, bcrypt, argon2 and scrypt are
+// supported.
+func Calibrate(algorithm string, target time.Duration, memoryCeiling uint64) (*HasherOption, error) {
+	if target <= 0 {
+		return nil, errIllegalParam("target", "should be positive")
+	}
+
+	switch algorithm {
+	case pbkdf2Sha256Algo, pbkdf2Sha1Algo, pbkdf2Sha512Algo:
+		return calibratePbkdf2(algorithm, target), nil
+	case bcryptAlgo, bcryptSha256Algo:
+		return calibrateBcrypt(algorithm, target)
+	case argon2Algo, argon2iAlgo, argon2dAlgo:
+		return calibrateArgon2(algorithm, target, memoryCeiling)
+	case scryptAlgo:
+		return calibrateScrypt(target, memoryCeiling)
+	}
+	if _, ok := lookupHasherFactory(algorithm); !ok {
+		return nil, errUnknownAlgorithmOf(algorithm)
+	}
+	return nil, ErrCalibrationUnsupported
+}
+
+// measure returns the shortest duration of f in `calibrationRounds` runs.
+func measure(f func()) time.Duration {
+	var fastest time.Duration
+	for i := 0; i < calibrationRounds; i++ {
+		start := time.Now()
+		f()
+		if d := time.Since(start); i == 0 || d < fastest {
+			fastest = d
+		}
+	}
+	if fastest <= 0 {
+		fastest = 1
+	}
+	return fastest
+}
+
+// scale returns n scaled by target/elapsed, the cost is linear to n.
+func scale(n int, target, elapsed time.Duration) int {
+	scaled := float64(n) * float64(target) / float64(elapsed)
+	if scaled > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(scaled)
+}
+
+func calibratePbkdf2(algorithm string, target time.Duration) *HasherOption {
+	const trial = 10000
+	size, newFunc := pbkdf2SizeAndNew(algorithm)
+	elapsed := measure(func() {
+		pbkdf2.Key([]byte(calibrationPassword), []byte(calibrationSalt), trial, size, newFunc)
+	})
+
+	floor := minPbkdf2Sha256Iterations
+	switch algorithm {
+	case pbkdf2Sha1Algo:
+		floor = minPbkdf2Sha1Iterations
+	case pbkdf2Sha512Algo:
+		floor = minPbkdf2Sha512Iterations
+	}
+	iterations := scale(trial, target, elapsed)
+	if iterations < floor {
+		iterations = floor
+	}
+	return &HasherOption{Algorithm: algorithm, Iterations: iterations}
+}
+
+func calibrateBcrypt(algorithm string, target time.Duration) (*HasherOption, error) {
+	var err error
+	elapsed := measure(func() {
+		_, err = bcrypt.GenerateFromPassword([]byte(calibrationPassword), bcrypt.MinCost)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every cost doubles the time
+	cost := bcrypt.MinCost
+	for cost < bcrypt.MaxCost && elapsed*2 <= target {
+		elapsed *= 2
+		cost++
+	}
+	if cost < minBcryptCost {
+		cost = minBcryptCost
+	}
+	return &HasherOption{Algorithm: algorithm, Iterations: cost}, nil
+}
+
+// calibrateArgon2 measures one iteration with the default memory at most, so
+// calibration never allocates the whole ceiling. The cost is linear to
+// memory, which is scaled up to the ceiling first, then iterations are
+// scaled for the rest of target.
+func calibrateArgon2(algorithm string, target time.Duration, memoryCeiling uint64) (*HasherOption, error) {
+	params := *defaultArgon2Params
+	ceiling := uint64(params.Memory)
+	if memoryCeiling > 0 {
+		if memoryCeiling/1024 < minArgon2Memory {
+			return nil, errIllegalParam("memory_ceiling", "should be at least 19 MiB for argon2")
+		}
+		ceiling = memoryCeiling / 1024
+		if ceiling > math.MaxUint32 {
+			ceiling = math.MaxUint32
+		}
+	}
+
+	trial := params.Memory
+	if uint64(trial) > ceiling {
+		trial = uint32(ceiling)
+	}
+	keyFunc := argon2KeyFuncs[algorithm]
+	elapsed := measure(func() {
+		keyFunc([]byte(calibrationPassword), []byte(calibrationSalt), 1, trial, params.Parallelism, params.KeyLength)
+	})
+
+	memory := uint64(scale(int(trial), target, elapsed))
+	if memory < minArgon2Memory {
+		memory = minArgon2Memory
+	}
+	if memory > ceiling {
+		memory = ceiling
+	}
+	params.Memory = uint32(memory)
+
+	elapsed = time.Duration(float64(elapsed) * float64(memory) / float64(trial))
+	params.Iterations = uint32(scale(1, target, elapsed))
+	if params.Iterations < 1 {
+		params.Iterations = 1
+	}
+	if params.Memory < minArgon2MemoryOneIteration && params.Iterations < 2 {
+		params.Iterations = 2
+	}
+	return &HasherOption{Algorithm: algorithm, Iterations: 1, Params: &params}, nil
+}
+
+// calibrateScrypt measures the default N at most like calibrateArgon2. N is
+// doubled up to the ceiling while it fits target, then parallelism, which
+// takes no more memory, is scaled for the rest of target.
+func calibrateScrypt(target time.Duration, memoryCeiling uint64) (*HasherOption, error) {
+	params := *defaultScryptParams
+	params.R = scryptCalibrationBlockLength
+	ceiling := uint64(params.N)
+	if memoryCeiling > 0 {
+		ceiling = memoryCeiling / (128 * uint64(params.R))
+		if ceiling < minScryptN {
+			return nil, errIllegalParam("memory_ceiling", "should be at least 16 MiB for scrypt")
+		}
+		if maxN := uint64(maxScryptMemory / (128 * params.R)); ceiling > maxN {
+			ceiling = maxN
+		}
+	}
+
+	var err error
+	elapsed := measure(func() {
+		_, err = scrypt.Key([]byte(calibrationPassword), []byte(calibrationSalt), params.N, params.R, 1, params.KeyLength)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the cost is linear to N, which stays a power of 2
+	for uint64(params.N)*2 <= ceiling && elapsed*2 <= target {
+		params.N *= 2
+		elapsed *= 2
+	}
+	params.P = scale(1, target, elapsed)
+	if params.P < 1 {
+		params.P = 1
+	}
+	if params.P > maxScryptP {
+		params.P = maxScryptP
+	}
+	return &HasherOption{Algorithm: scryptAlgo, Iterations: 1, Params: &params}, nil
+}
@@ -0,0 +1,105 @@
+package password
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalibrateFloors(t *testing.T) {
+	data := []struct {
+		algorithm     string
+		memoryCeiling uint64
+		iterations    int
+		params        interface{}
+	}{
+		{pbkdf2Sha256Algo, 0, minPbkdf2Sha256Iterations, nil},
+		{pbkdf2Sha1Algo, 0, minPbkdf2Sha1Iterations, nil},
+		{pbkdf2Sha512Algo, 0, minPbkdf2Sha512Iterations, nil},
+		{bcryptAlgo, 0, minBcryptCost, nil},
+		{argon2Algo, 19 << 20, 1, Argon2Params{Memory: minArgon2Memory, Iterations: 2, Parallelism: 4, SaltLength: 16, KeyLength: 32}},
+		{scryptAlgo, 16 << 20, 1, ScryptParams{N: minScryptN, R: 8, P: 1, KeyLength: 64}},
+	}
+
+	for _, d := range data {
+		// too short target gets the minimums
+		opt, err := Calibrate(d.algorithm, time.Nanosecond, d.memoryCeiling)
+		if err != nil {
+			t.Fatalf("Calibrate(%s) should be ok: %s", d.algorithm, err)
+		}
+		if opt.Algorithm != d.algorithm || opt.Iterations != d.iterations {
+			t.Errorf("Calibrate(%s) should have %d iterations: %+v", d.algorithm, d.iterations, opt)
+		}
+		switch p := opt.Params.(type) {
+		case *Argon2Params:
+			if *p != d.params {
+				t.Errorf("Calibrate(%s) params should be %+v: %+v", d.algorithm, d.params, *p)
+			}
+		case *ScryptParams:
+			if *p != d.params {
+				t.Errorf("Calibrate(%s) params should be %+v: %+v", d.algorithm, d.params, *p)
+			}
+		}
+		if _, err = NewHasher(opt); err != nil {
+			t.Errorf("NewHasher(%+v) should be ok: %s", opt, err)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	ceiling := uint64(32 << 20)
+	opt, err := Calibrate(argon2Algo, time.Second, ceiling)
+	if err != nil {
+		t.Fatalf("Calibrate should be ok: %s", err)
+	}
+	if p := opt.Params.(*Argon2Params); uint64(p.Memory) != ceiling/1024 || p.Iterations < 2 {
+		t.Errorf("memory should be the ceiling and iterations should be scaled: %+v", p)
+	}
+
+	// the ceiling is not allocated by the measurement
+	opt, err = Calibrate(argon2Algo, time.Millisecond, 1<<40)
+	if err != nil {
+		t.Fatalf("Calibrate should be ok: %s", err)
+	}
+	if p := opt.Params.(*Argon2Params); p.Memory != minArgon2Memory {
+		t.Errorf("memory should be the minimum: %+v", p)
+	}
+
+	opt, err = Calibrate(scryptAlgo, time.Millisecond, 1<<40)
+	if err != nil {
+		t.Fatalf("Calibrate should be ok: %s", err)
+	}
+	if p := opt.Params.(*ScryptParams); p.N != minScryptN || p.P != 1 {
+		t.Errorf("n should be the minimum: %+v", p)
+	}
+	opt, err = Calibrate(scryptAlgo, 10*time.Second, 32<<20)
+	if err != nil {
+		t.Fatalf("Calibrate should be ok: %s", err)
+	}
+	if p := opt.Params.(*ScryptParams); uint64(128*p.N*p.R) != 32<<20 || p.P < 2 {
+		t.Errorf("n should be the ceiling and p should be scaled: %+v", p)
+	}
+
+	opt, err = Calibrate(bcryptAlgo, time.Hour, 0)
+	if err != nil || opt.Iterations <= minBcryptCost {
+		t.Errorf("cost should be scaled: %+v %s", opt, err)
+	}
+
+	for _, d := range []struct {
+		algorithm     string
+		target        time.Duration
+		memoryCeiling uint64
+	}{
+		{bcryptAlgo, 0, 0},
+		{argon2Algo, time.Second, 1 << 20},
+		{scryptAlgo, time.Second, 1 << 20},
+		{md5Algo, time.Second, 0},
+		{"unknown", time.Second, 0},
+	} {
+		if _, err = Calibrate(d.algorithm, d.target, d.memoryCeiling); err == nil {
+			t.Errorf("Calibrate(%+v) should be error", d)
+		}
+	}
+	if _, err = Calibrate(md5Algo, time.Second, 0); err != ErrCalibrationUnsupported {
+		t.Errorf("Calibrate(md5) should be ErrCalibrationUnsupported: %s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package password
+
+import "context"
+
+// ContextHasher is implemented by hashers which may wait before hashing,
+// such as a limiter, so that waiting can be cancelled by ctx.
+type ContextHasher interface {
+	EncodeContext(ctx context.Context, password string) (string, error)
+	VerifyContext(ctx context.Context, password, encoded string) (bool, error)
+}
+
+// EncodeContext encodes password with hasher, unless ctx is done, in which
+// case `ctx.Err()` is returned. Hashing itself cannot be interrupted once it
+// has started.
+func EncodeContext(ctx context.Context, hasher Hasher, password string) (string, error) {
+	if h, ok := hasher.(ContextHasher); ok {
+		return h.EncodeContext(ctx, password)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return hasher.Encode(password)
+}
+
+// VerifyContext verifies password with hasher like `EncodeContext`, err is
+// `ctx.Err()` if verifying is not started because ctx is done.
+func VerifyContext(ctx context.Context, hasher Hasher, password, encoded string) (bool, error) {
+	if h, ok := hasher.(ContextHasher); ok {
+		return h.VerifyContext(ctx, password, encoded)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return hasher.Verify(password, encoded), nil
+}
+
+// EncodeContext encodes password with the preferred hasher, see `EncodeContext`.
+func (m *PasswordManager) EncodeContext(ctx context.Context, password string) (string, error) {
+	return EncodeContext(ctx, m.hashers[m.preferred], password)
+}
+
+// VerifyContext verifies password with the hasher of encoded, see
+// `VerifyContext`. err is *UnknownAlgorithmError if the algorithm of encoded
+// is not managed.
+func (m *PasswordManager) VerifyContext(ctx context.Context, password, encoded string) (bool, error) {
+	hasher, err := m.hasherOf(encoded)
+	if err != nil {
+		return false, err
+	}
+	return VerifyContext(ctx, hasher, password, encoded)
+}
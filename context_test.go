@@ -0,0 +1,38 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000})
+	m, _ := NewPasswordManager(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000})
+
+	for _, h := range []Hasher{hasher, m} {
+		encoded, err := EncodeContext(context.Background(), h, password)
+		if err != nil {
+			t.Fatalf("EncodeContext should be ok: %s", err)
+		}
+		if ok, err := VerifyContext(context.Background(), h, password, encoded); !ok || err != nil {
+			t.Errorf("VerifyContext should be true: %s", err)
+		}
+		if ok, err := VerifyContext(context.Background(), h, "wrong", encoded); ok || err != nil {
+			t.Errorf("VerifyContext(wrong) should be false: %s", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err = EncodeContext(ctx, h, password); err != context.Canceled {
+			t.Errorf("EncodeContext should be canceled: %s", err)
+		}
+		if ok, err := VerifyContext(ctx, h, password, encoded); ok || err != context.Canceled {
+			t.Errorf("VerifyContext should be canceled: %s", err)
+		}
+	}
+
+	if ok, err := m.VerifyContext(context.Background(), password, "unknown$abc"); ok || !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("VerifyContext(unknown) should be ErrUnknownAlgorithm: %s", err)
+	}
+}
@@ -0,0 +1,359 @@
+package password
+
+import (
+	"crypto/md5" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"hash"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// crypt(3) strings of /etc/shadow:
+//
+//	$6$[rounds=<rounds>$]<salt>$<hash>	sha512_crypt
+//	$5$[rounds=<rounds>$]<salt>$<hash>	sha256_crypt
+//	$1$<salt>$<hash>			md5_crypt
+//
+// See https://www.akkadia.org/drepper/SHA-crypt.txt. md5_crypt is obsolete,
+// it always must be updated.
+const (
+	sha512CryptAlgo = "sha512_crypt"
+	sha256CryptAlgo = "sha256_crypt"
+	md5CryptAlgo    = "md5_crypt"
+)
+
+// cryptIdentifiers maps identifiers of crypt(3) strings to algorithms.
+var cryptIdentifiers = map[string]string{
+	"6": sha512CryptAlgo,
+	"5": sha256CryptAlgo,
+	"1": md5CryptAlgo,
+}
+
+const (
+	cryptRoundsPrefix = "rounds="
+	// rounds of sha-crypt, defaultCryptRounds if rounds are omitted
+	defaultCryptRounds = 5000
+	minCryptRounds     = 1000
+	maxCryptRounds     = 999999999
+	md5CryptRounds     = 1000
+
+	maxShaCryptSaltLength = 16
+	maxMd5CryptSaltLength = 8
+)
+
+// cryptEncoding is the base64 alphabet of crypt(3), bytes are encoded in
+// little-endian groups of 24 bits.
+const cryptEncoding = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// cryptPermutations are the orders of digest bytes in groups of 3, the last
+// group may have less bytes.
+var cryptPermutations = map[string][][]int{
+	sha512CryptAlgo: {
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+		{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+		{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+		{63},
+	},
+	sha256CryptAlgo: {
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14}, {15, 25, 5}, {6, 16, 26},
+		{27, 7, 17}, {18, 28, 8}, {9, 19, 29}, {31, 30},
+	},
+	md5CryptAlgo: {
+		{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}, {11},
+	},
+}
+
+// cryptEncode encodes digest in the order of algorithm.
+func cryptEncode(algorithm string, digest []byte) string {
+	var sb strings.Builder
+	for _, group := range cryptPermutations[algorithm] {
+		var w uint
+		for _, i := range group {
+			w = w<<8 | uint(digest[i])
+		}
+		for n := len(group) + 1; n > 0; n-- {
+			sb.WriteByte(cryptEncoding[w&0x3f])
+			w >>= 6
+		}
+	}
+	return sb.String()
+}
+
+// CryptInfo is Others of PasswordInfo decoded from crypt(3) strings.
+type CryptInfo struct {
+	// ExplicitRounds: whether `rounds=` is in the string, which is kept by
+	// crypt(3) even if it is the default
+	ExplicitRounds bool
+}
+
+type cryptHasher struct {
+	algo   string
+	rounds int
+}
+
+func (hasher *cryptHasher) Encode(password string) (string, error) {
+	length := maxShaCryptSaltLength
+	if hasher.algo == md5CryptAlgo {
+		length = maxMd5CryptSaltLength
+	}
+	salt, err := generateSalt(length)
+	if err != nil {
+		return "", err
+	}
+	return cryptString(hasher.algo, password, salt, hasher.rounds, hasher.rounds != defaultCryptRounds), nil
+}
+
+// cryptString returns crypt(3) string of password, rounds are ignored by md5_crypt.
+func cryptString(algorithm, password, salt string, rounds int, explicitRounds bool) string {
+	id := ""
+	for i, algo := range cryptIdentifiers {
+		if algo == algorithm {
+			id = i
+		}
+	}
+
+	parts := []string{"", id}
+	if explicitRounds && algorithm != md5CryptAlgo {
+		parts = append(parts, cryptRoundsPrefix+strconv.Itoa(rounds))
+	}
+	parts = append(parts, salt, cryptEncode(algorithm, cryptDigest(algorithm, password, salt, rounds)))
+	return strings.Join(parts, sep)
+}
+
+// cryptDigest computes the digest of password, rounds are ignored by md5_crypt.
+func cryptDigest(algorithm, password, salt string, rounds int) []byte {
+	switch algorithm {
+	case sha512CryptAlgo:
+		return shaCrypt(sha512.New, []byte(password), []byte(salt), rounds)
+	case sha256CryptAlgo:
+		return shaCrypt(sha256.New, []byte(password), []byte(salt), rounds)
+	case md5CryptAlgo:
+		return md5Crypt([]byte(password), []byte(salt))
+	}
+	return nil
+}
+
+// shaCrypt computes the digest of sha-crypt.
+func shaCrypt(newHash func() hash.Hash, password, salt []byte, rounds int) []byte {
+	size := newHash().Size()
+
+	// digest B of password, salt, password
+	h := newHash()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+	b := h.Sum(nil)
+
+	// digest A
+	h = newHash()
+	h.Write(password)
+	h.Write(salt)
+	h.Write(repeatBytes(b, len(password)))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write(b)
+		} else {
+			h.Write(password)
+		}
+	}
+	a := h.Sum(nil)
+
+	// byte sequence P of digest DP
+	h = newHash()
+	for i := 0; i < len(password); i++ {
+		h.Write(password)
+	}
+	p := repeatBytes(h.Sum(nil), len(password))
+
+	// byte sequence S of digest DS
+	h = newHash()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write(salt)
+	}
+	s := repeatBytes(h.Sum(nil), len(salt))
+
+	c := a
+	for i := 0; i < rounds; i++ {
+		h = newHash()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(c[:0])
+	}
+	return c[:size]
+}
+
+// md5Crypt computes the digest of md5-crypt.
+func md5Crypt(password, salt []byte) []byte {
+	h := md5.New() // #nosec
+	h.Write(password)
+	h.Write(salt)
+	h.Write(password)
+	alt := h.Sum(nil)
+
+	h = md5.New() // #nosec
+	h.Write(password)
+	h.Write([]byte(sep + "1" + sep))
+	h.Write(salt)
+	h.Write(repeatBytes(alt, len(password)))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(password[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := 0; i < md5CryptRounds; i++ {
+		h = md5.New() // #nosec
+		if i&1 != 0 {
+			h.Write(password)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write(salt)
+		}
+		if i%7 != 0 {
+			h.Write(password)
+		}
+		if i&1 != 0 {
+			h.Write(final)
+		} else {
+			h.Write(password)
+		}
+		final = h.Sum(final[:0])
+	}
+	return final
+}
+
+// repeatBytes returns b repeated to length n.
+func repeatBytes(b []byte, n int) []byte {
+	r := make([]byte, 0, n)
+	for len(r)+len(b) < n {
+		r = append(r, b...)
+	}
+	return append(r, b[:n-len(r)]...)
+}
+
+// isCrypt reports whether encoded is a crypt(3) string of sha-crypt or md5-crypt.
+func isCrypt(encoded string) bool {
+	if !strings.HasPrefix(encoded, sep) {
+		return false
+	}
+	_, ok := cryptIdentifiers[strings.SplitN(encoded[len(sep):], sep, 2)[0]]
+	return ok
+}
+
+// Decode decodes crypt(3) strings, Iterations of PasswordInfo are the rounds,
+// and Others is *CryptInfo.
+func (hasher *cryptHasher) Decode(encoded string) (*PasswordInfo, error) {
+	if !isCrypt(encoded) {
+		return nil, errUnknownAlgorithmOf(strings.SplitN(encoded, sep, 2)[0])
+	}
+	parts := strings.Split(encoded, sep)
+	algo := cryptIdentifiers[parts[1]]
+
+	rounds, explicit := defaultCryptRounds, false
+	if algo == md5CryptAlgo {
+		rounds = md5CryptRounds
+	} else if len(parts) > 2 && strings.HasPrefix(parts[2], cryptRoundsPrefix) {
+		r, err := strconv.Atoi(parts[2][len(cryptRoundsPrefix):])
+		if err != nil {
+			return nil, errMalformed("rounds", err)
+		}
+		if r < minCryptRounds || r > maxCryptRounds {
+			return nil, errIllegalParam("rounds", "should be in [1000, 999999999]")
+		}
+		rounds, explicit = r, true
+		parts = append(parts[:2], parts[3:]...)
+	}
+	if len(parts) != 4 {
+		return nil, ErrMalformedEncoded
+	}
+
+	maxSaltLength := maxShaCryptSaltLength
+	if algo == md5CryptAlgo {
+		maxSaltLength = maxMd5CryptSaltLength
+	}
+	if len(parts[2]) > maxSaltLength {
+		return nil, errMalformed("salt", nil)
+	}
+	if len(parts[3]) != len(cryptEncode(algo, make([]byte, 64))) || strings.Trim(parts[3], cryptEncoding) != "" {
+		return nil, errMalformed("hash", nil)
+	}
+
+	return &PasswordInfo{
+		Algorithm:  algo,
+		Iterations: rounds,
+		Salt:       parts[2],
+		Hash:       parts[3],
+		Others:     &CryptInfo{ExplicitRounds: explicit},
+	}, nil
+}
+
+func (hasher *cryptHasher) Verify(password, encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+	// only the hash is compared, `rounds=5000` may be explicit or not
+	computed := cryptEncode(pi.Algorithm, cryptDigest(pi.Algorithm, password, pi.Salt, pi.Iterations))
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(pi.Hash)) == 1
+}
+
+// MustUpdate returns true for md5_crypt, or if algorithm differs from the
+// configured one, rounds are less, or salt gives less than `saltEntropy` bits
+// of `cryptEncoding`. The 8 characters salt of md5_crypt never give enough.
+func (hasher *cryptHasher) MustUpdate(encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+	return pi.Algorithm == md5CryptAlgo || pi.Algorithm != hasher.algo || pi.Iterations < hasher.rounds ||
+		float64(len(pi.Salt))*math.Log2(float64(len(cryptEncoding))) < saltEntropy
+}
+
+func (hasher *cryptHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+// newCryptHasher makes a crypt(3) hasher, rounds of sha-crypt are Iterations
+// of opt, at least `defaultCryptRounds`.
+func newCryptHasher(opt *HasherOption) (Hasher, error) {
+	if opt.Format != FormatDefault {
+		return nil, ErrUnsupportedFormat
+	}
+	if opt.Iterations > maxCryptRounds {
+		return nil, ErrIllegalIterations
+	}
+
+	rounds := defaultCryptRounds
+	if opt.Iterations > rounds {
+		rounds = opt.Iterations
+	}
+	return &cryptHasher{algo: opt.Algorithm, rounds: rounds}, nil
+}
+
+func init() {
+	for _, algo := range []string{sha512CryptAlgo, sha256CryptAlgo, md5CryptAlgo} {
+		mustRegisterHasher(algo, newCryptHasher)
+	}
+}
@@ -0,0 +1,137 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// vectors of SHA-crypt.txt and glibc crypt(3)
+var cryptPasswords = []struct {
+	algo     string
+	password string
+	encoded  string
+}{
+	{md5CryptAlgo, "Hello world!", "$1$saltsalt$le8lFSqqnPaRFOlmAZpvH1"},
+	{md5CryptAlgo, "password", "$1$9Cq1LRsb$RY0iOynOtgF/K0kdFtAPK0"},
+	{sha256CryptAlgo, "Hello world!", "$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+	{sha256CryptAlgo, "Hello world!", "$5$rounds=10000$saltstringsaltst$3xv.VbSHBb41AL9AvLeujZkZRBAwqFMz2.opqey6IcA"},
+	{sha256CryptAlgo, strings.Repeat("a", 100), "$5$saltstring$HE80cZIBUxLL.bS7Ud8vy2RfFEVIyvfBaoUr.RekKl3"},
+	{sha512CryptAlgo, "Hello world!", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+	{sha512CryptAlgo, "Hello world!", "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+	{sha512CryptAlgo, "Hello world!", "$6$rounds=1400$anotherlongsalts$5FGyu8c4BZDX4wJgs0Un26YOw2XibT5eTkHF1I1aP3QqStoJI9BHD2YPJYsAjEePVGUyBjdZxcNqMWlrrbIOC."},
+	{sha512CryptAlgo, "", "$6$saltstring$kyGrqt6gmjAdtFLPrflEFifSYLCWWq1pyx95SvqinLDy2UHmj0sTF0MSLMwxPFZc3tu5kQckI8fks0zOPda3n1"},
+}
+
+func TestVerifyCrypt(t *testing.T) {
+	hasher, err := NewHasher(&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 1})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, d := range cryptPasswords {
+		pi, err := hasher.Decode(d.encoded)
+		if err != nil || pi.Algorithm != d.algo {
+			t.Fatalf("Decode(%s) should be %s: %+v %s", d.encoded, d.algo, pi, err)
+		}
+		if !hasher.Verify(d.password, d.encoded) {
+			t.Errorf("Verify(%s) should be true", d.encoded)
+		}
+		if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+			t.Errorf("Check(wrong, %s) should be ErrMismatch: %s", d.encoded, err)
+		}
+		if algo, err := Identify(d.encoded); err != nil || algo != d.algo {
+			t.Errorf("Identify(%s) should be %s: %s %s", d.encoded, d.algo, algo, err)
+		}
+		if !Verify(d.password, d.encoded) {
+			t.Errorf("default PasswordManager should verify %s", d.encoded)
+		}
+	}
+
+	pi, _ := hasher.Decode("$6$rounds=656000$Zb4Q2vHnLaYQTkuD$eG35lNFg2Fw/Du9QHelxJL94hVbm6ey1rqdGGCxHIhMN1XkTE2dQhYzma8r9kFlPXwlS9QJEa2rNre/mysdPw/")
+	if pi.Iterations != 656000 || !pi.Others.(*CryptInfo).ExplicitRounds {
+		t.Errorf("rounds should be 656000: %+v", pi)
+	}
+	// rounds=5000 is the default, which may be explicit
+	explicit := "$6$rounds=5000$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"
+	if !hasher.Verify("Hello world!", explicit) {
+		t.Errorf("Verify(%s) should be true", explicit)
+	}
+
+	// bcrypt is not crypt(3) of this hasher
+	if algo, _ := Identify("$2a$10$dXJ3SW6G7P50lGmMkkmwe.20cQQubK3.HZWzG3YB1tlRy.fqvM/BG"); algo != bcryptAlgo {
+		t.Errorf("$2a$ should be bcrypt: %s", algo)
+	}
+	if !Verify("Hello world!", "{CRYPT}"+cryptPasswords[5].encoded) {
+		t.Error("{CRYPT} of sha512_crypt should be verified")
+	}
+}
+
+func TestEncodeCrypt(t *testing.T) {
+	data := []struct {
+		opt    *HasherOption
+		prefix string
+	}{
+		{&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 1}, "$6$"},
+		{&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 10000}, "$6$rounds=10000$"},
+		{&HasherOption{Algorithm: sha256CryptAlgo, Iterations: 1}, "$5$"},
+		{&HasherOption{Algorithm: md5CryptAlgo, Iterations: 1}, "$1$"},
+	}
+	for _, d := range data {
+		t.Run(d.prefix, func(t *testing.T) {
+			hasher, err := NewHasher(d.opt)
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			encoded, err := hasher.Encode(password)
+			if err != nil || !strings.HasPrefix(encoded, d.prefix) {
+				t.Fatalf("encoded should start with %s: %s %s", d.prefix, encoded, err)
+			}
+			if !hasher.Verify(password, encoded) {
+				t.Error("Verify() should be true")
+			}
+			// md5_crypt is obsolete
+			if hasher.MustUpdate(encoded) != (d.opt.Algorithm == md5CryptAlgo) {
+				t.Errorf("MustUpdate(%s) should be %v", encoded, d.opt.Algorithm == md5CryptAlgo)
+			}
+		})
+	}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 10000})
+	if !hasher.MustUpdate(cryptPasswords[5].encoded) || !hasher.MustUpdate(cryptPasswords[2].encoded) {
+		t.Error("should update less rounds and other algorithms")
+	}
+	// 11 characters of crypt(3) give 66 bits, 10 give 60 bits
+	hasher, _ = NewHasher(&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 1})
+	hash := "svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"
+	if hasher.MustUpdate("$6$saltstring.$" + hash) {
+		t.Error("should not update 11 characters salt")
+	}
+	if !hasher.MustUpdate("$6$saltstring$" + hash) {
+		t.Error("should update 10 characters salt")
+	}
+	if _, err := NewHasher(&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 1, Format: FormatPHC}); err != ErrUnsupportedFormat {
+		t.Errorf("crypt(3) should not support PHC format: %s", err)
+	}
+}
+
+func TestIllegalCrypt(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: sha512CryptAlgo, Iterations: 1})
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"$7$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl", ErrUnknownAlgorithm},
+		{"$6$rounds=10$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", ErrIllegalParam},
+		{"$6$rounds=x$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", ErrMalformedEncoded},
+		{"$6$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", ErrMalformedEncoded},
+		{"$6$saltstringsaltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1", ErrMalformedEncoded},
+		{"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl", ErrMalformedEncoded},
+		{"$1$saltsalt$le8lFSqqnPaRFOlmAZpvH!", ErrMalformedEncoded},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+}
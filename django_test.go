@@ -0,0 +1,135 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDjangoArgon2(t *testing.T) {
+	// stored by Django's Argon2PasswordHasher
+	django := "argon2$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+	params := &Argon2Params{Memory: 65536, Iterations: 2, Parallelism: 4, SaltLength: 8, KeyLength: 24}
+
+	hasher, err := NewHasher(&HasherOption{Algorithm: argon2iAlgo, Iterations: 1, Params: params, Format: FormatDjango})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+	pi, err := hasher.Decode(django)
+	if err != nil || pi.Algorithm != argon2iAlgo || *pi.Others.(*Argon2Params) != *params {
+		t.Fatalf("Decode(django) should be ok: %+v %s", pi, err)
+	}
+	if !hasher.Verify("password", django) || hasher.MustUpdate(django) {
+		t.Error("Verify(django) should be true without update")
+	}
+	if algo, err := Identify(django); err != nil || algo != argon2iAlgo {
+		t.Errorf("Identify(django) should be %s: %s %s", argon2iAlgo, algo, err)
+	}
+	if !Verify("password", django) {
+		t.Error("default PasswordManager should verify django argon2")
+	}
+
+	encoded, _ := hasher.Encode(password)
+	if !strings.HasPrefix(encoded, "argon2$argon2i$v=19$m=65536,t=2,p=4$") {
+		t.Errorf("encoded should be in django format: %s", encoded)
+	}
+	if !hasher.Verify(password, encoded) {
+		t.Error("Verify() should be true")
+	}
+
+	defaultHasher, _ := NewHasher(&HasherOption{Algorithm: argon2iAlgo, Iterations: 1, Params: params})
+	if !defaultHasher.Verify(password, encoded) || !defaultHasher.MustUpdate(encoded) {
+		t.Error("default format should verify and update django format")
+	}
+}
+
+func TestDjangoArgon2Defaults(t *testing.T) {
+	// Django's defaults: m=102400, t=2, p=8, 22 characters of salt and 16 bytes of hash
+	django := "argon2$argon2id$v=19$m=102400,t=2,p=8$elJxOFBEdE5FMG1KM2tVVng2ZjJXYg$9vhr5d2PmfaGTYIxu12sKg"
+
+	hasher, err := NewHasher(&HasherOption{Algorithm: argon2Algo, Iterations: 1, Format: FormatDjango})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+	if !hasher.Verify("lètmein", django) {
+		t.Error("Verify(django) should be true")
+	}
+	if hasher.MustUpdate(django) {
+		t.Error("should not update django argon2 of the defaults")
+	}
+
+	encoded, _ := hasher.Encode(password)
+	if !strings.HasPrefix(encoded, "argon2$argon2id$v=19$m=102400,t=2,p=8$") || hasher.MustUpdate(encoded) {
+		t.Errorf("encoded should have the django defaults: %s", encoded)
+	}
+
+	// weaker iterations of the hasher are not a reason to update
+	weak, _ := NewHasher(&HasherOption{Algorithm: argon2Algo, Iterations: 1, Format: FormatDjango, Params: map[string]interface{}{"iterations": 1}})
+	if weak.MustUpdate(django) {
+		t.Error("should not update a stronger django argon2")
+	}
+	strong, _ := NewHasher(&HasherOption{Algorithm: argon2Algo, Iterations: 1, Format: FormatDjango, Params: map[string]interface{}{"iterations": 3}})
+	if !strong.MustUpdate(django) {
+		t.Error("should update django argon2 of less iterations")
+	}
+}
+
+func TestDjangoFormat(t *testing.T) {
+	for _, algo := range []string{pbkdf2Sha256Algo, scryptAlgo, bcryptAlgo, bcryptSha256Algo, md5Algo, sha1Algo} {
+		hasher, err := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1, Format: FormatDjango})
+		if err != nil {
+			t.Fatalf("NewHasher(%s) should support django format: %s", algo, err)
+		}
+		encoded, _ := hasher.Encode(password)
+		if !strings.HasPrefix(encoded, algo+"$") || hasher.MustUpdate(encoded) {
+			t.Errorf("%s should be encoded like default format: %s", algo, encoded)
+		}
+	}
+
+	if _, err := NewHasher(&HasherOption{Algorithm: unsaltedMd5Algo, Iterations: 1, Format: FormatDjango}); err != ErrUnsupportedFormat {
+		t.Errorf("unsalted_md5 should not support django format: %s", err)
+	}
+}
+
+func TestDjangoBcryptSha256(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: bcryptSha256Algo, Iterations: 10})
+	encoded, _ := hasher.Encode(password)
+
+	// Django hashes hex SHA-256 digest
+	hash := strings.TrimPrefix(encoded, bcryptSha256Algo+sep)
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), sha256Hex(password)); err != nil {
+		t.Errorf("bcrypt_sha256 should hash hex digest: %s", err)
+	}
+
+	// legacy passwords hashed over raw digest are verified and upgraded
+	raw, _ := bcrypt.GenerateFromPassword(sha256Raw(password), 10)
+	legacy := bcryptSha256Algo + sep + string(raw)
+	if !hasher.Verify(password, legacy) || hasher.Verify("wrong", legacy) {
+		t.Error("Verify(legacy) should verify raw digest")
+	}
+	if hasher.MustUpdate(legacy) {
+		t.Error("MustUpdate can't tell legacy without password")
+	}
+
+	ok, newEncoded, err := VerifyAndUpgrade(hasher, password, legacy, nil)
+	if !ok || err != nil || len(newEncoded) == 0 {
+		t.Fatalf("VerifyAndUpgrade(legacy) should upgrade: %v %s", ok, err)
+	}
+	newHash := strings.TrimPrefix(newEncoded, bcryptSha256Algo+sep)
+	if err = bcrypt.CompareHashAndPassword([]byte(newHash), sha256Hex(password)); err != nil {
+		t.Errorf("upgraded password should hash hex digest: %s", err)
+	}
+	if ok, newEncoded, _ = VerifyAndUpgrade(hasher, password, encoded, nil); !ok || len(newEncoded) > 0 {
+		t.Error("hex digest password should not be upgraded")
+	}
+
+	if hardened, err := hasher.Harden(password, legacy); err != nil || hardened == legacy {
+		t.Errorf("Harden(legacy) should re-encode: %s", err)
+	}
+
+	m, _ := NewPasswordManager(&HasherOption{Algorithm: bcryptSha256Algo, Iterations: 10, Secret: "pepper"})
+	if ok, newEncoded, _ := m.VerifyAndUpgrade(password, legacy, nil); !ok || !strings.HasPrefix(newEncoded, "pepper$1$bcrypt_sha256$") {
+		t.Errorf("PasswordManager should upgrade legacy password: %s", newEncoded)
+	}
+}
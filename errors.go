@@ -5,36 +5,245 @@ import (
 	"fmt"
 )
 
-// Unknown algorithm.
-var errUnknownAlgorithm = errors.New("unknown algorithm")
+// Error codes are stable and machine-readable, see `ErrorCode`.
+const (
+	CodeUnknownAlgorithm       = "unknown_algorithm"
+	CodeUnknownFormat          = "unknown_format"
+	CodeUnsupportedFormat      = "unsupported_format"
+	CodeIllegalSalt            = "illegal_salt"
+	CodeIllegalSaltLength      = "illegal_salt_length"
+	CodeIllegalIterations      = "illegal_iterations"
+	CodeIllegalParam           = "illegal_param"
+	CodeMalformedEncoded       = "malformed_encoded"
+	CodeMismatch               = "mismatch"
+	CodeIllegalMinLength       = "illegal_min_length"
+	CodeIllegalMaxLength       = "illegal_max_length"
+	CodeMinMaxLength           = "min_max_length"
+	CodeCommonPassword         = "common_password"
+	CodePasswordTooShort       = "password_too_short"
+	CodePasswordTooLong        = "password_too_long"
+	CodePasswordRequirement    = "password_requirement"
+	CodeExceedsLength          = "exceeds_length"
+	CodeBlankAlgorithm         = "blank_algorithm"
+	CodeNilHasherFactory       = "nil_hasher_factory"
+	CodeAlgorithmRegistered    = "algorithm_registered"
+	CodeReservedAlgorithm      = "reserved_algorithm"
+	CodeNilHasherOption        = "nil_hasher_option"
+	CodeNoHasherOption         = "no_hasher_option"
+	CodeDuplicateAlgorithm     = "duplicate_algorithm"
+	CodeUnknownPepper          = "unknown_pepper"
+	CodeIllegalPepperID        = "illegal_pepper_id"
+	CodeBlankPepper            = "blank_pepper"
+	CodeNotWrappedHasher       = "not_wrapped_hasher"
+	CodeCalibrationUnsupported = "calibration_unsupported"
+	CodeLimitExceeded          = "limit_exceeded"
+)
+
+// codeError is a sentinel error with a code.
+type codeError struct {
+	code    string
+	message string
+}
+
+func newError(code, message string) error {
+	return &codeError{code: code, message: message}
+}
+
+func (e *codeError) Error() string {
+	return e.message
+}
+
+func (e *codeError) Code() string {
+	return e.code
+}
+
+// ErrorCode returns the code of err or any error it wraps, blank if err is
+// nil or not an error of this package.
+func ErrorCode(err error) string {
+	var c interface{ Code() string }
+	if errors.As(err, &c) {
+		return c.Code()
+	}
+	return ""
+}
+
+// ErrUnknownAlgorithm algorithm is not supported, see `*UnknownAlgorithmError`.
+var ErrUnknownAlgorithm = newError(CodeUnknownAlgorithm, "unknown algorithm")
+
+// ErrUnknownFormat format is not supported.
+var ErrUnknownFormat = newError(CodeUnknownFormat, "unknown format")
+
+// ErrUnsupportedFormat format is not supported by the algorithm.
+var ErrUnsupportedFormat = newError(CodeUnsupportedFormat, "format is not supported by the algorithm")
+
+// ErrIllegalSalt salt cannot contain '$'.
+var ErrIllegalSalt = newError(CodeIllegalSalt, "salt cannot contain '$'")
+
+// ErrIllegalSaltLength SaltLength should give at least `saltEntropy` bits entropy.
+var ErrIllegalSaltLength = newError(CodeIllegalSaltLength, fmt.Sprintf("salt_length should be at least %d", minSaltLength))
+
+// ErrIllegalIterations Iterations should be greater than 0.
+var ErrIllegalIterations = newError(CodeIllegalIterations, "iterations should be greater than 0")
 
-// Salt cannot contain '$'.
-var errIllegalSalt = errors.New("salt cannot contain '$'")
+// ErrIllegalParam param is illegal or out of range, see `*ParamError`.
+var ErrIllegalParam = newError(CodeIllegalParam, "illegal param")
 
-// Iterations should be gratter than 0.
-var errIllegalIterations = errors.New("iterations should be gratter than 0")
+// ErrMalformedEncoded encoded password cannot be parsed, see `*MalformedError`.
+var ErrMalformedEncoded = newError(CodeMalformedEncoded, "malformed encoded password")
 
-// Salt must be provided and cannot contain $.
-var errBlankSalt = errors.New("salt must be provided and cannot contain $")
+// ErrMismatch is returned by Check if password does not match encoded.
+var ErrMismatch = newError(CodeMismatch, "password does not match")
 
-// MinLength should less than MaxLength
-var errMinMax = errors.New("min_length should less than max_length")
+// ErrIllegalMinLength MinLength should be more than 0.
+var ErrIllegalMinLength = newError(CodeIllegalMinLength, "min_length should be more than 0")
 
-// MinLength should more than 0
-var errMin = errors.New("min_length should more than 0")
+// ErrIllegalMaxLength MaxLength should be at most `maxLengthPassword`.
+var ErrIllegalMaxLength = newError(CodeIllegalMaxLength, fmt.Sprintf("max_length should be at most %d", maxLengthPassword))
 
-// MinLength should more than 0
-var errMax = errors.New(fmt.Sprintf("max_length should less than %d", maxLengthPassword))
+// ErrMinMaxLength MinLength should be less than MaxLength.
+var ErrMinMaxLength = newError(CodeMinMaxLength, "min_length should be less than max_length")
 
-// This password is too common
-var errCommon = errors.New("this password is too common")
+// ErrCommonPassword this password is too common.
+var ErrCommonPassword = newError(CodeCommonPassword, "this password is too common")
+
+// ErrPasswordTooShort password is shorter than MinLength, see `*LengthError`.
+var ErrPasswordTooShort = newError(CodePasswordTooShort, "this password is too short")
+
+// ErrPasswordTooLong password is longer than MaxLength, see `*LengthError`.
+var ErrPasswordTooLong = newError(CodePasswordTooLong, "this password is too long")
+
+// ErrPasswordRequirement password misses required characters, see `*RequirementError`.
+var ErrPasswordRequirement = newError(CodePasswordRequirement, "this password misses required characters")
+
+// ErrLimitExceeded waiting for a Limiter is too long, see `*LimitError`.
+var ErrLimitExceeded = newError(CodeLimitExceeded, "password hashing limit exceeded")
+
+// Param `name` of HasherOption.Params or encoded password is illegal or out of range.
+func errIllegalParam(name, reason string) error {
+	return &ParamError{Name: name, Reason: reason}
+}
+
+// Field of encoded password cannot be parsed.
+func errMalformed(field string, err error) error {
+	return &MalformedError{Field: field, Err: err}
+}
+
+// Algorithm is not supported.
+func errUnknownAlgorithmOf(algorithm string) error {
+	return &UnknownAlgorithmError{Algorithm: algorithm}
+}
+
+// MalformedError field of encoded password cannot be parsed.
+// It matches ErrMalformedEncoded by `errors.Is`.
+type MalformedError struct {
+	// Field: name of the field, such as salt, hash, iterations
+	Field string
+	// Err: the cause, may be nil
+	Err error
+}
+
+func (e *MalformedError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("malformed encoded password: illegal %s", e.Field)
+	}
+	return fmt.Sprintf("malformed encoded password: illegal %s: %s", e.Field, e.Err)
+}
+
+func (e *MalformedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *MalformedError) Is(target error) bool {
+	return target == ErrMalformedEncoded
+}
+
+func (e *MalformedError) Code() string {
+	return CodeMalformedEncoded
+}
+
+// UnknownAlgorithmError algorithm of encoded password or HasherOption is not supported.
+// It matches ErrUnknownAlgorithm by `errors.Is`.
+type UnknownAlgorithmError struct {
+	Algorithm string
+}
+
+func (e *UnknownAlgorithmError) Error() string {
+	return fmt.Sprintf("unknown algorithm: %q", e.Algorithm)
+}
+
+func (e *UnknownAlgorithmError) Is(target error) bool {
+	return target == ErrUnknownAlgorithm
+}
+
+func (e *UnknownAlgorithmError) Code() string {
+	return CodeUnknownAlgorithm
+}
+
+// ParamError param of HasherOption.Params or encoded password is illegal or out of range.
+// It matches ErrIllegalParam by `errors.Is`.
+type ParamError struct {
+	Name   string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("illegal param %s: %s", e.Name, e.Reason)
+}
+
+func (e *ParamError) Is(target error) bool {
+	return target == ErrIllegalParam
+}
+
+func (e *ParamError) Code() string {
+	return CodeIllegalParam
+}
+
+// LengthError length of password is out of [Min, Max].
+// It matches ErrPasswordTooShort or ErrPasswordTooLong by `errors.Is`.
+type LengthError struct {
+	Min    int
+	Max    int
+	Actual int
+}
+
+func (e *LengthError) Error() string {
+	if e.Actual < e.Min {
+		return fmt.Sprintf("this password is too short. It must contain at least %d characters", e.Min)
+	}
+	return fmt.Sprintf("this password is too long. It must contain at most %d characters", e.Max)
+}
+
+func (e *LengthError) Is(target error) bool {
+	if e.Actual < e.Min {
+		return target == ErrPasswordTooShort
+	}
+	return target == ErrPasswordTooLong
+}
+
+func (e *LengthError) Code() string {
+	if e.Actual < e.Min {
+		return CodePasswordTooShort
+	}
+	return CodePasswordTooLong
+}
+
+// RequirementError password misses required characters.
+// It matches ErrPasswordRequirement by `errors.Is`.
+type RequirementError struct {
+	// Missing: required but missing kinds of characters, such as digits,
+	// lower letters, upper letters, letters, punctuations
+	Missing []string
+	message string
+}
+
+func (e *RequirementError) Error() string {
+	return e.message
+}
 
-// At least `length` characters.
-func errMinLength(length int) error {
-	return fmt.Errorf("this password is too short. It must contain at least %d characters", length)
+func (e *RequirementError) Is(target error) bool {
+	return target == ErrPasswordRequirement
 }
 
-// At most `length` characters.
-func errMaxLength(length int) error {
-	return fmt.Errorf("this password is too long. It must contain at most %d characters", length)
+func (e *RequirementError) Code() string {
+	return CodePasswordRequirement
 }
@@ -0,0 +1,65 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	data := []struct {
+		err  error
+		code string
+	}{
+		{nil, ""},
+		{errors.New("other"), ""},
+		{ErrMismatch, CodeMismatch},
+		{ErrIllegalIterations, CodeIllegalIterations},
+		{fmt.Errorf("wrapped: %w", ErrUnknownPepper), CodeUnknownPepper},
+		{errUnknownAlgorithmOf("sha512"), CodeUnknownAlgorithm},
+		{errMalformed("salt", nil), CodeMalformedEncoded},
+		{errIllegalParam("n", "too small"), CodeIllegalParam},
+		{&LengthError{Min: 8, Max: 16, Actual: 4}, CodePasswordTooShort},
+		{&LengthError{Min: 8, Max: 16, Actual: 20}, CodePasswordTooLong},
+		{&RequirementError{Missing: []string{"digits"}}, CodePasswordRequirement},
+		{&LimitError{}, CodeLimitExceeded},
+	}
+	for _, d := range data {
+		if code := ErrorCode(d.err); code != d.code {
+			t.Errorf("ErrorCode(%v) should be %s: %s", d.err, d.code, code)
+		}
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	data := []struct {
+		err      error
+		sentinel error
+	}{
+		{errUnknownAlgorithmOf("sha512"), ErrUnknownAlgorithm},
+		{errMalformed("salt", nil), ErrMalformedEncoded},
+		{errIllegalParam("n", "too small"), ErrIllegalParam},
+		{&LengthError{Min: 8, Max: 16, Actual: 4}, ErrPasswordTooShort},
+		{&LengthError{Min: 8, Max: 16, Actual: 20}, ErrPasswordTooLong},
+		{&RequirementError{}, ErrPasswordRequirement},
+		{&LimitError{}, ErrLimitExceeded},
+	}
+	for _, d := range data {
+		if !errors.Is(d.err, d.sentinel) {
+			t.Errorf("%v should be %v", d.err, d.sentinel)
+		}
+	}
+	if errors.Is(&LengthError{Min: 8, Max: 16, Actual: 4}, ErrPasswordTooLong) {
+		t.Error("too short password should not be ErrPasswordTooLong")
+	}
+
+	v, _ := NewValidator(&ValidatorOption{MinLength: 8, MaxLength: 16, RequireDigit: true, RequireUppercase: true})
+	var le *LengthError
+	if err := v.Validate("short"); !errors.As(err, &le) || le.Min != 8 || le.Max != 16 || le.Actual != 5 {
+		t.Errorf("Validate(short) should be *LengthError: %v", err)
+	}
+	var re *RequirementError
+	if err := v.Validate("lowercase1"); !errors.As(err, &re) || len(re.Missing) != 1 || re.Missing[0] != "upper letters" {
+		t.Errorf("Validate(lowercase1) should be *RequirementError: %v", err)
+	}
+}
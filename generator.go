@@ -2,7 +2,6 @@ package password
 
 import (
 	"crypto/rand"
-	"errors"
 	"math/big"
 	mrand "math/rand"
 	"time"
@@ -156,7 +155,8 @@ func MustGenerate(length, minDigitLength, minSymbolLength, minUpperLetter uint)
 	return defaultGenerator.MustGenerate(length, minDigitLength, minSymbolLength, minUpperLetter)
 }
 
-var ErrExceedsLength = errors.New("number of digits, symbols and upper letters must be less than total length")
+// ErrExceedsLength required characters are more than length.
+var ErrExceedsLength = newError(CodeExceedsLength, "number of digits, symbols and upper letters must be less than total length")
 
 func check(length, minDigitLength, minSymbolLength, minUpperLetter uint) error {
 	if minDigitLength+minSymbolLength+minUpperLetter > length {
@@ -1,7 +1,5 @@
 package password
 
-import "errors"
-
 type Hasher interface {
 	Encode(password string) (string, error)
 	Decode(decoded string) (*PasswordInfo, error)
@@ -10,11 +8,12 @@ type Hasher interface {
 	Harden(password, encoded string) (string, error)
 }
 
-var errNilHasherOption = errors.New("nil HasherOption")
+// ErrNilHasherOption HasherOption of NewHasher is nil.
+var ErrNilHasherOption = newError(CodeNilHasherOption, "nil HasherOption")
 
 func NewHasher(opt *HasherOption) (Hasher, error) {
 	if opt == nil {
-		return nil, errNilHasherOption
+		return nil, ErrNilHasherOption
 	}
 	err := opt.validate()
 	if err != nil {
@@ -31,4 +30,110 @@ type PasswordInfo struct {
 	Iterations int
 	Salt       string
 	Others     interface{}
+	// PepperID: id of the pepper, blank if encoded without pepper
+	PepperID string
+}
+
+// upgradeVerifier is implemented by hashers which verify legacy encoded
+// passwords that `MustUpdate` can't tell without password, such as
+// bcrypt_sha256 over raw digest.
+type upgradeVerifier interface {
+	// verifyUpgrade returns whether password matches encoded, and whether
+	// encoded must be updated.
+	verifyUpgrade(password, encoded string) (ok, mustUpdate bool)
+}
+
+// verifyUpgrade verifies password, and reports whether encoded must be updated.
+func verifyUpgrade(hasher Hasher, password, encoded string) (bool, bool) {
+	if v, ok := hasher.(upgradeVerifier); ok {
+		return v.verifyUpgrade(password, encoded)
+	}
+	if !hasher.Verify(password, encoded) {
+		return false, false
+	}
+	return true, hasher.MustUpdate(encoded)
+}
+
+// VerifyAndUpgrade verifies password, and re-encodes it if `MustUpdate` says so.
+//
+// newEncoded is blank if password is wrong or encoded needs no update.
+// persist, if not nil, is called with newEncoded to save it, and its error is
+// returned. Pass a PasswordManager as hasher to upgrade passwords encoded by
+// legacy algorithms to the preferred one.
+func VerifyAndUpgrade(hasher Hasher, password, encoded string, persist func(newEncoded string) error) (ok bool, newEncoded string, err error) {
+	ok, mustUpdate := verifyUpgrade(hasher, password, encoded)
+	if !ok {
+		return false, "", nil
+	}
+	if !mustUpdate {
+		return true, "", nil
+	}
+
+	newEncoded, err = hasher.Encode(password)
+	if err != nil {
+		return true, "", err
+	}
+	if persist != nil {
+		if err = persist(newEncoded); err != nil {
+			return true, newEncoded, err
+		}
+	}
+	return true, newEncoded, nil
+}
+
+// harden re-encodes password with the current parameters of hasher if
+// encoded is weaker, which is reported by `MustUpdate`, otherwise encoded is
+// returned. password is verified before re-encoding.
+func harden(hasher Hasher, password, encoded string) (string, error) {
+	if _, err := hasher.Decode(encoded); err != nil {
+		return "", err
+	}
+	if v, ok := hasher.(upgradeVerifier); ok {
+		matched, mustUpdate := v.verifyUpgrade(password, encoded)
+		if !matched {
+			return "", ErrMismatch
+		}
+		if !mustUpdate {
+			return encoded, nil
+		}
+		return hasher.Encode(password)
+	}
+	if !hasher.MustUpdate(encoded) {
+		return encoded, nil
+	}
+	if !hasher.Verify(password, encoded) {
+		return "", ErrMismatch
+	}
+	return hasher.Encode(password)
+}
+
+// checker is implemented by hashers which tell more than `checkPassword` why
+// encoded cannot be verified, such as unknown peppers.
+type checker interface {
+	// Check returns nil if password matches encoded, ErrMismatch if not, or
+	// why encoded cannot be verified: *MalformedError, *UnknownAlgorithmError
+	// or *ParamError.
+	Check(password, encoded string) error
+}
+
+// CheckWith verifies password with hasher like `Hasher.Verify`, and returns
+// nil, ErrMismatch, or why encoded cannot be verified: *MalformedError,
+// *UnknownAlgorithmError or *ParamError.
+func CheckWith(hasher Hasher, password, encoded string) error {
+	if c, ok := hasher.(checker); ok {
+		return c.Check(password, encoded)
+	}
+	return checkPassword(hasher, password, encoded)
+}
+
+// checkPassword decodes encoded to report why it cannot be verified, then verifies
+// password. Decode of hashers validates all fields used by Verify.
+func checkPassword(hasher Hasher, password, encoded string) error {
+	if _, err := hasher.Decode(encoded); err != nil {
+		return err
+	}
+	if !hasher.Verify(password, encoded) {
+		return ErrMismatch
+	}
+	return nil
 }
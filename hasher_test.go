@@ -1,6 +1,10 @@
 package password
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestNewHasher(t *testing.T) {
 	var opt *HasherOption
@@ -8,7 +12,317 @@ func TestNewHasher(t *testing.T) {
 	_, err := NewHasher(opt)
 	if err == nil {
 		t.Errorf("NewHasher(nil) should be error")
-	} else if err != errNilHasherOption {
-		t.Errorf("NewHasher(nil) should be errNilHasherOption: %s", err)
+	} else if err != ErrNilHasherOption {
+		t.Errorf("NewHasher(nil) should be ErrNilHasherOption: %s", err)
+	}
+}
+
+// TestVerifyIgnoresFormatting makes sure hashers compare derived keys instead
+// of re-serialized encoded passwords.
+func TestVerifyIgnoresFormatting(t *testing.T) {
+	smallArgon2 := &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	zeroPad := func(index int) func(parts []string) {
+		return func(parts []string) {
+			parts[index] = "0" + parts[index]
+		}
+	}
+	upper := func(indexes ...int) func(parts []string) {
+		return func(parts []string) {
+			for _, i := range indexes {
+				parts[i] = strings.ToUpper(parts[i])
+			}
+		}
+	}
+	pad := func(indexes ...int) func(parts []string) {
+		return func(parts []string) {
+			for _, i := range indexes {
+				for len(parts[i])%4 != 0 {
+					parts[i] += "="
+				}
+			}
+		}
+	}
+	reverseParams := func(index int) func(parts []string) {
+		return func(parts []string) {
+			params := strings.Split(parts[index], ",")
+			for i, j := 0, len(params)-1; i < j; i, j = i+1, j-1 {
+				params[i], params[j] = params[j], params[i]
+			}
+			parts[index] = strings.Join(params, ",")
+		}
+	}
+
+	data := []struct {
+		name    string
+		opt     *HasherOption
+		mutates []func(parts []string)
+	}{
+		{
+			name:    "argon2id",
+			opt:     &HasherOption{Algorithm: argon2Algo, Iterations: 1, Params: smallArgon2},
+			mutates: []func(parts []string){upper(1, 6), zeroPad(2), zeroPad(3)},
+		},
+		{
+			name:    "argon2id phc",
+			opt:     &HasherOption{Algorithm: argon2Algo, Iterations: 1, Params: smallArgon2, Format: FormatPHC},
+			mutates: []func(parts []string){pad(4, 5), reverseParams(3)},
+		},
+		{
+			name:    "pbkdf2_sha256",
+			opt:     &HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000},
+			mutates: []func(parts []string){zeroPad(1)},
+		},
+		{
+			name:    "pbkdf2_sha1 phc",
+			opt:     &HasherOption{Algorithm: pbkdf2Sha1Algo, Iterations: 1000, Format: FormatPHC},
+			mutates: []func(parts []string){pad(3, 4)},
+		},
+		{
+			name:    "scrypt",
+			opt:     &HasherOption{Algorithm: scryptAlgo, Iterations: 1},
+			mutates: []func(parts []string){zeroPad(1), zeroPad(3), zeroPad(4)},
+		},
+		{
+			name:    "scrypt phc",
+			opt:     &HasherOption{Algorithm: scryptAlgo, Iterations: 1, Format: FormatPHC},
+			mutates: []func(parts []string){reverseParams(2), pad(3, 4)},
+		},
+		{
+			name:    "md5",
+			opt:     &HasherOption{Algorithm: md5Algo, Iterations: 1},
+			mutates: []func(parts []string){upper(2)},
+		},
+		{
+			name:    "sha1",
+			opt:     &HasherOption{Algorithm: sha1Algo, Iterations: 1},
+			mutates: []func(parts []string){upper(2)},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			hasher, err := NewHasher(d.opt)
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			encoded, _ := hasher.Encode(password)
+
+			for i, mutate := range d.mutates {
+				parts := strings.Split(encoded, sep)
+				mutate(parts)
+				mutated := strings.Join(parts, sep)
+				if mutated == encoded {
+					t.Fatalf("mutation %d should change encoded: %s", i, encoded)
+				}
+				if !hasher.Verify(password, mutated) {
+					t.Errorf("Verify(%s) should be true", mutated)
+				}
+				if hasher.Verify("wrong", mutated) {
+					t.Errorf("Verify(wrong, %s) should be false", mutated)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	legacyHasher, _ := NewHasher(&HasherOption{Algorithm: md5Algo, Iterations: 1})
+	legacy, _ := legacyHasher.Encode(password)
+
+	m, _ := NewPasswordManager(
+		&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000},
+		&HasherOption{Algorithm: md5Algo, Iterations: 1},
+	)
+
+	persisted := ""
+	persist := func(newEncoded string) error {
+		persisted = newEncoded
+		return nil
+	}
+
+	ok, newEncoded, err := m.VerifyAndUpgrade("wrong", legacy, persist)
+	if ok || newEncoded != "" || err != nil || persisted != "" {
+		t.Errorf("wrong password should not be upgraded: %t %s %s", ok, newEncoded, err)
+	}
+
+	ok, newEncoded, err = m.VerifyAndUpgrade(password, legacy, persist)
+	if !ok || err != nil {
+		t.Fatalf("VerifyAndUpgrade should be ok: %t %s", ok, err)
+	}
+	if algo, _ := m.Identify(newEncoded); algo != pbkdf2Sha256Algo {
+		t.Errorf("should be upgraded to %s: %s", pbkdf2Sha256Algo, newEncoded)
+	}
+	if persisted != newEncoded {
+		t.Errorf("persist should be called with %s: %s", newEncoded, persisted)
+	}
+	if !m.Verify(password, newEncoded) {
+		t.Error("upgraded password should be verified")
+	}
+
+	ok, newEncoded, err = VerifyAndUpgrade(m, password, persisted, nil)
+	if !ok || newEncoded != "" || err != nil {
+		t.Errorf("up-to-date password should not be upgraded: %t %s %s", ok, newEncoded, err)
+	}
+
+	errPersist := errors.New("persist error")
+	ok, newEncoded, err = VerifyAndUpgrade(m, password, legacy, func(string) error {
+		return errPersist
+	})
+	if !ok || newEncoded == "" || err != errPersist {
+		t.Errorf("persist error should be returned: %t %s %s", ok, newEncoded, err)
+	}
+}
+
+func TestHarden(t *testing.T) {
+	data := []struct {
+		name   string
+		weak   *HasherOption
+		strong *HasherOption
+	}{
+		{
+			name: "argon2id",
+			weak: &HasherOption{Algorithm: argon2Algo, Iterations: 1,
+				Params: &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 16}},
+			strong: &HasherOption{Algorithm: argon2Algo, Iterations: 1,
+				Params: &Argon2Params{Memory: 2048, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		},
+		{
+			name: "argon2i to argon2id",
+			weak: &HasherOption{Algorithm: argon2iAlgo, Iterations: 1,
+				Params: &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+			strong: &HasherOption{Algorithm: argon2Algo, Iterations: 1,
+				Params: &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		},
+		{
+			name:   "bcrypt",
+			weak:   &HasherOption{Algorithm: bcryptAlgo, Iterations: 10},
+			strong: &HasherOption{Algorithm: bcryptAlgo, Iterations: 11},
+		},
+		{
+			name:   "pbkdf2_sha256",
+			weak:   &HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000},
+			strong: &HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 2000},
+		},
+		{
+			name:   "scrypt",
+			weak:   &HasherOption{Algorithm: scryptAlgo, Iterations: 1, Params: &ScryptParams{N: 1024, R: 8, P: 1, KeyLength: 64}},
+			strong: &HasherOption{Algorithm: scryptAlgo, Iterations: 1, Params: &ScryptParams{N: 2048, R: 8, P: 1, KeyLength: 64}},
+		},
+		{
+			name:   "md5",
+			weak:   &HasherOption{Algorithm: md5Algo, Iterations: 1, SaltLength: 11},
+			strong: &HasherOption{Algorithm: md5Algo, Iterations: 1, SaltLength: 22},
+		},
+		{
+			name:   "unsalted_md5 to md5",
+			weak:   &HasherOption{Algorithm: unsaltedMd5Algo, Iterations: 1},
+			strong: &HasherOption{Algorithm: md5Algo, Iterations: 1},
+		},
+		{
+			name:   "sha1",
+			weak:   &HasherOption{Algorithm: sha1Algo, Iterations: 1, SaltLength: 11},
+			strong: &HasherOption{Algorithm: sha1Algo, Iterations: 1, SaltLength: 22},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.name, func(t *testing.T) {
+			weak, _ := NewHasher(d.weak)
+			strong, err := NewHasher(d.strong)
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			encoded, _ := weak.Encode(password)
+			if !strong.MustUpdate(encoded) {
+				t.Fatal("weak password should be updated")
+			}
+
+			if _, err = strong.Harden("wrong", encoded); err != ErrMismatch {
+				t.Errorf("Harden(wrong) should be ErrMismatch: %s", err)
+			}
+
+			hardened, err := strong.Harden(password, encoded)
+			if err != nil {
+				t.Fatalf("Harden should be ok: %s", err)
+			}
+			if hardened == encoded {
+				t.Fatal("Harden should re-encode weak password")
+			}
+			if !strong.Verify(password, hardened) {
+				t.Error("hardened password should be verified")
+			}
+			if strong.MustUpdate(hardened) {
+				t.Error("hardened password should not be updated")
+			}
+
+			again, err := strong.Harden(password, hardened)
+			if err != nil || again != hardened {
+				t.Errorf("Harden should return strong password unchanged: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000})
+	encoded, _ := hasher.Encode(password)
+	if err := CheckWith(hasher, password, encoded); err != nil {
+		t.Errorf("Check should be nil: %s", err)
+	}
+	if err := Check(password, encoded); err != nil {
+		t.Errorf("Check with default PasswordManager should be nil: %s", err)
+	}
+	if err := CheckWith(hasher, "wrong", encoded); err != ErrMismatch {
+		t.Errorf("Check(wrong) should be ErrMismatch: %s", err)
+	}
+
+	malformed := []struct {
+		encoded string
+		field   string
+	}{
+		{"argon2id$zz$1$1024$1$32$00112233445566778899aabbccddeeff", "salt"},
+		{"argon2id$0011$x$1024$1$32$00112233445566778899aabbccddeeff", "iterations"},
+		{"argon2id$0011$1$1024$1$32$zz", "hash"},
+		{"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$!!", "hash"},
+		{"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$aGFzaA", "m"},
+		{"pbkdf2_sha256$many$salt$aGFzaA==", "iterations"},
+		{"pbkdf2_sha256$1000$salt$!!", "hash"},
+		{"scrypt$1024$salt$8$1$!!", "hash"},
+		{"md5$salt$zz", "hash"},
+		{"sha1$salt$zz", "hash"},
+		{"bcrypt$$2a$10$short", "hash"},
+	}
+	for _, m := range malformed {
+		err := Check(password, m.encoded)
+		var e *MalformedError
+		if !errors.As(err, &e) || e.Field != m.field {
+			t.Errorf("Check(%s) should be *MalformedError of %s: %s", m.encoded, m.field, err)
+		}
+		if !errors.Is(err, ErrMalformedEncoded) {
+			t.Errorf("Check(%s) should be ErrMalformedEncoded: %s", m.encoded, err)
+		}
+	}
+
+	for _, encoded := range []string{
+		"argon2id$0011$0$1024$1$32$00112233445566778899aabbccddeeff",
+		"$argon2id$v=19$t=1,p=1$c2FsdA$aGFzaA",
+		"pbkdf2_sha256$0$salt$aGFzaA==",
+		"scrypt$1000$salt$8$1$aGFzaA==",
+	} {
+		var e *ParamError
+		if err := Check(password, encoded); !errors.As(err, &e) {
+			t.Errorf("Check(%s) should be *ParamError: %s", encoded, err)
+		}
+	}
+
+	err := Check(password, "sha512$salt$hash")
+	var e *UnknownAlgorithmError
+	if !errors.As(err, &e) || e.Algorithm != "sha512" || !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Check(sha512) should be *UnknownAlgorithmError: %s", err)
+	}
+
+	peppered, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000, Secret: "pepper"})
+	if err = CheckWith(peppered, password, "pepper$2$"+encoded); err != ErrUnknownPepper {
+		t.Errorf("Check(unknown pepper) should be ErrUnknownPepper: %s", err)
 	}
 }
@@ -1,82 +1,151 @@
 package password
 
 import (
+	"encoding/json"
 	"strings"
 )
 
+// saltEntropy is the minimum entropy in bits of salts.
 const saltEntropy = 64
 
+// defaultSaltLength gives about 128 bits entropy.
+const defaultSaltLength = 22
+
+// minSaltLength is the shortest random salt giving `saltEntropy` bits entropy.
+const minSaltLength = 11
+
 const (
 	md5Algo          = "md5"
 	unsaltedMd5Algo  = "unsalted_md5"
 	pbkdf2Sha256Algo = "pbkdf2_sha256"
 	pbkdf2Sha1Algo   = "pbkdf2_sha1"
+	pbkdf2Sha512Algo = "pbkdf2_sha512"
 	argon2Algo       = "argon2id"
+	argon2iAlgo      = "argon2i"
+	argon2dAlgo      = "argon2d"
 	bcryptAlgo       = "bcrypt"
 	bcryptSha256Algo = "bcrypt_sha256"
 	scryptAlgo       = "scrypt"
 	sha1Algo         = "sha1"
 )
 
-var supportAlgorithms = map[string]struct{}{
-	md5Algo:          {},
-	unsaltedMd5Algo:  {},
-	pbkdf2Sha256Algo: {},
-	pbkdf2Sha1Algo:   {},
-	argon2Algo:       {},
-	bcryptAlgo:       {},
-	bcryptSha256Algo: {},
-	scryptAlgo:       {},
-	sha1Algo:         {},
-}
-
 // HasherOption Hasher option
 type HasherOption struct {
 	// Algorithm: Support md5, unsalted_md5, pbkdf2_sha256, pbkdf2_sha1,
-	// argon2id, bcrypt, bcrypt_sha256, scrypt, sha1
+	// pbkdf2_sha512, argon2id, argon2i, argon2d, bcrypt, bcrypt_sha256, scrypt,
+	// sha1, wrapped, aspnet_identity, spring, ldap_ssha512, ldap_ssha256,
+	// ldap_ssha, ldap_sha, ldap_smd5, ldap_md5, ldap_crypt, sha512_crypt,
+	// sha256_crypt, md5_crypt, and algorithms registered by `RegisterHasher`
 	Algorithm string `json:"algorithm"`
 
+	// Secret: pepper applied to passwords by HMAC before they are encoded,
+	// passwords are encoded without pepper if blank.
 	Secret string `json:"secret"`
+	// SecretID: id of Secret stored in encoded passwords, "1" if blank.
+	SecretID string `json:"secret_id"`
+	// OldSecrets: retired peppers by id, which only verify passwords.
+	OldSecrets map[string]string `json:"old_secrets"`
+	// Peppers: provides peppers instead of Secret, SecretID and OldSecrets.
+	Peppers PepperProvider `json:"-"`
 
-	// Salt: cannot contain '$'
+	// Salt: cannot contain '$'. pbkdf2, scrypt, md5 and sha1 hashers generate
+	// a random salt for every password, Salt is only used to recognise
+	// passwords encoded with it, which must be updated.
 	Salt string `json:"salt"`
-	// Iterations: should be gratter than 0
-	Iterations int         `json:"iterations"`
-	Params     interface{} `json:"params"`
+	// SaltLength: length of random salts of pbkdf2, scrypt, md5 and sha1, 22
+	// if 0, at least 11 for 64 bits entropy. argon2 uses
+	// `Argon2Params.SaltLength` instead, spring uses the delegate option, and
+	// bcrypt, aspnet_identity, LDAP and crypt(3) hashers use the salt length
+	// of their formats.
+	SaltLength int `json:"salt_length"`
+	// Iterations: should be greater than 0
+	Iterations int `json:"iterations"`
+	// Params: params of the algorithm, such as *Argon2Params, *ScryptParams,
+	// or the *HasherOption of the outer algorithm for wrapped, and of the
+	// delegate algorithm for spring.
+	Params interface{} `json:"params"`
+
+	// Format: format of encoded password, FormatDefault, FormatPHC,
+	// FormatDjango, FormatWerkzeug or FormatPasslib.
+	// Decode and Verify accept all formats supported by the algorithm.
+	Format string `json:"format"`
 }
 
 func (ho *HasherOption) validate() error {
-	if _, ok := supportAlgorithms[ho.Algorithm]; !ok {
-		return errUnknownAlgorithm
+	if _, ok := lookupHasherFactory(ho.Algorithm); !ok {
+		return errUnknownAlgorithmOf(ho.Algorithm)
 	}
 
 	if strings.Contains(ho.Salt, sep) {
-		return errIllegalSalt
+		return ErrIllegalSalt
 	}
 
 	if ho.Iterations <= 0 {
-		return errIllegalIterations
+		return ErrIllegalIterations
+	}
+
+	if ho.SaltLength < 0 || (ho.SaltLength > 0 && ho.SaltLength < minSaltLength) {
+		return ErrIllegalSaltLength
+	}
+
+	if _, ok := supportFormats[ho.Format]; !ok {
+		return ErrUnknownFormat
 	}
 
 	return nil
 }
 
 func (ho *HasherOption) NewHasher() (Hasher, error) {
-	var hasher Hasher
-	var err error
-	switch ho.Algorithm {
-	case unsaltedMd5Algo, md5Algo:
-		hasher, err = newMD5Hasher(ho)
-	case pbkdf2Sha1Algo, pbkdf2Sha256Algo:
-		hasher, err = newPBKDDF2Hasher(ho)
-	case argon2Algo:
-		hasher, err = newArgon2Hasher(ho)
-	case bcryptSha256Algo, bcryptAlgo:
-		hasher, err = newBcryptHasher(ho)
-	case scryptAlgo:
-		hasher, err = newScryptHasher(ho)
-	case sha1Algo:
-		hasher, err = newSha1Hasher(ho)
+	factory, ok := lookupHasherFactory(ho.Algorithm)
+	if !ok {
+		return nil, errUnknownAlgorithmOf(ho.Algorithm)
+	}
+	hasher, err := factory(ho)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := pepperProviderOf(ho)
+	if err != nil {
+		return nil, err
+	}
+	if provider != nil {
+		hasher = &pepperHasher{hasher: hasher, provider: provider}
+	}
+	return hasher, nil
+}
+
+// saltOption generates random salts, and checks salts of encoded passwords.
+type saltOption struct {
+	// static is the application salt of HasherOption.
+	static string
+	length int
+}
+
+func newSaltOption(opt *HasherOption) saltOption {
+	length := opt.SaltLength
+	if length == 0 {
+		length = defaultSaltLength
+	}
+	return saltOption{static: opt.Salt, length: length}
+}
+
+func (so saltOption) generate() (string, error) {
+	return generateSalt(so.length)
+}
+
+// mustUpdate reports whether salt is the static application salt,
+// or weaker than the configured one.
+func (so saltOption) mustUpdate(salt string) bool {
+	return salt == so.static || mustUpdateSalt(salt, saltEntropy) || len(salt) < so.length
+}
+
+// decodeParams decodes `HasherOption.Params`, such as map[string]interface{}
+// unmarshalled from JSON, into params. Fields missing in v are kept.
+func decodeParams(v interface{}, params interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
 	}
-	return hasher, err
+	return json.Unmarshal(b, params)
 }
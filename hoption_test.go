@@ -16,15 +16,95 @@ func TestHasherOption(t *testing.T) {
 	err := ho.validate()
 	if err == nil {
 		t.Errorf("Salt: ho.validate() should be error")
-	} else if err != errIllegalSalt {
-		t.Errorf("Salt: error should be errIllegalSalt: %s", err)
+	} else if err != ErrIllegalSalt {
+		t.Errorf("Salt: error should be ErrIllegalSalt: %s", err)
 	}
 
 	ho.Salt = "adfedfd"
 	err = ho.validate()
 	if err == nil {
 		t.Errorf("Iterations: ho.validate() should be error")
-	} else if err != errIllegalIterations {
-		t.Errorf("Iterations: error should be errIllegalIterations: %s", err)
+	} else if err != ErrIllegalIterations {
+		t.Errorf("Iterations: error should be ErrIllegalIterations: %s", err)
+	}
+}
+
+func TestIllegalSaltLength(t *testing.T) {
+	for _, length := range []int{-1, 1, 10} {
+		ho := &HasherOption{Algorithm: md5Algo, Iterations: 1, SaltLength: length}
+		if err := ho.validate(); err != ErrIllegalSaltLength {
+			t.Errorf("SaltLength %d: error should be ErrIllegalSaltLength: %s", length, err)
+		}
+	}
+
+	ho := &HasherOption{Algorithm: md5Algo, Iterations: 1, SaltLength: 11}
+	if err := ho.validate(); err != nil {
+		t.Errorf("SaltLength 11: ho.validate() should be ok: %s", err)
+	}
+
+	if saltEntropyOf(minSaltLength) < saltEntropy || saltEntropyOf(minSaltLength-1) >= saltEntropy {
+		t.Errorf("minSaltLength %d should be the shortest for %d bits entropy", minSaltLength, saltEntropy)
+	}
+}
+
+func TestRandomSalt(t *testing.T) {
+	const staticSalt = "appsaltappsaltappsalt1"
+	data := []struct {
+		algorithm string
+		legacy    string
+		params    interface{}
+	}{
+		{
+			algorithm: md5Algo,
+			legacy:    "md5$appsaltappsaltappsalt1$6930233195aa81a250f759a1ee897178",
+		},
+		{
+			algorithm: sha1Algo,
+			legacy:    "sha1$appsaltappsaltappsalt1$d75f8050eb7c79fbe62611423e0c84ed68c5b27c",
+		},
+		{
+			algorithm: pbkdf2Sha256Algo,
+			legacy:    "pbkdf2_sha256$1000$appsaltappsaltappsalt1$xEiplakfTDhx7NMt9t9I1pq8NfNLLQpgC7VWA/BZwoc=",
+		},
+		{
+			algorithm: scryptAlgo,
+			legacy:    "scrypt$1024$appsaltappsaltappsalt1$8$1$v5Nwij40YG99U+q56dT6pRkA/r5x1UquqkfU0SfW9GjNb9/E8z4oHVXltf4S3Dua8+QNamtUbOo87cQRzWvJZA==",
+			params:    &ScryptParams{N: 1024, R: 8, P: 1, KeyLength: 64},
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{
+				Algorithm:  d.algorithm,
+				Salt:       staticSalt,
+				SaltLength: 16,
+				Iterations: 1000,
+				Params:     d.params,
+			})
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+
+			encoded1, _ := hasher.Encode(password)
+			encoded2, _ := hasher.Encode(password)
+			if encoded1 == encoded2 {
+				t.Error("same password should be encoded with different salts")
+			}
+			pi, _ := hasher.Decode(encoded1)
+			if len(pi.Salt) != 16 || pi.Salt == staticSalt {
+				t.Errorf("salt should be random: %s", pi.Salt)
+			}
+			if !hasher.Verify(password, encoded1) || hasher.MustUpdate(encoded1) {
+				t.Error("random salt password should be verified and not updated")
+			}
+
+			if !hasher.Verify(password, d.legacy) {
+				t.Error("static salt password should be verified")
+			}
+			if !hasher.MustUpdate(d.legacy) {
+				t.Error("static salt password should be updated")
+			}
+		})
 	}
 }
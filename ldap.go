@@ -0,0 +1,224 @@
+package password
+
+import (
+	"crypto/md5"  // #nosec
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LDAP userPassword schemes of RFC 2307:
+//
+//	{SSHA512}<base64 of digest and salt>
+//	{SHA}<base64 of digest>
+//	{CRYPT}<crypt(3) string>
+//
+// Salted digests hash the password followed by the salt. The schemes are
+// case-insensitive, and {CRYPT} supports bcrypt, sha512_crypt, sha256_crypt
+// and md5_crypt, passwords are encoded with bcrypt.
+const (
+	ldapShaAlgo     = "ldap_sha"
+	ldapSshaAlgo    = "ldap_ssha"
+	ldapSsha256Algo = "ldap_ssha256"
+	ldapSsha512Algo = "ldap_ssha512"
+	ldapMd5Algo     = "ldap_md5"
+	ldapSmd5Algo    = "ldap_smd5"
+	ldapCryptAlgo   = "ldap_crypt"
+)
+
+// ldapSaltLength is the length in bytes of salts of encoded passwords.
+const ldapSaltLength = 16
+
+type ldapScheme struct {
+	scheme string
+	// newHash is nil for {CRYPT}
+	newHash func() hash.Hash
+	salted  bool
+}
+
+// ldapSchemes maps algorithms to LDAP schemes.
+var ldapSchemes = map[string]ldapScheme{
+	ldapShaAlgo:     {"{SHA}", sha1.New, false},
+	ldapSshaAlgo:    {"{SSHA}", sha1.New, true},
+	ldapSsha256Algo: {"{SSHA256}", sha256.New, true},
+	ldapSsha512Algo: {"{SSHA512}", sha512.New, true},
+	ldapMd5Algo:     {"{MD5}", md5.New, false},
+	ldapSmd5Algo:    {"{SMD5}", md5.New, true},
+	ldapCryptAlgo:   {"{CRYPT}", nil, false},
+}
+
+// splitLDAP returns the algorithm of the scheme of encoded and the rest,
+// blank algorithm if the scheme is not supported.
+func splitLDAP(encoded string) (algo, rest string) {
+	for a, s := range ldapSchemes {
+		if len(encoded) >= len(s.scheme) && strings.EqualFold(encoded[:len(s.scheme)], s.scheme) {
+			return a, encoded[len(s.scheme):]
+		}
+	}
+	return "", encoded
+}
+
+// ldapAlgorithmOf returns the algorithm of LDAP encoded password, blank if
+// encoded is not a LDAP password. Digests are checked, so that `{MD5}` of
+// Spring Security is not taken as LDAP.
+func ldapAlgorithmOf(encoded string) string {
+	algo, rest := splitLDAP(encoded)
+	if len(algo) == 0 {
+		return ""
+	}
+	if _, err := decodeLDAP(algo, rest); err != nil {
+		return ""
+	}
+	return algo
+}
+
+// decodeLDAP decodes encoded without scheme.
+func decodeLDAP(algo, encoded string) (*PasswordInfo, error) {
+	s := ldapSchemes[algo]
+	if s.newHash == nil {
+		hasher := ldapCryptHasherOf(encoded)
+		if hasher == nil {
+			return nil, errMalformed("hash", nil)
+		}
+		inner, err := hasher.Decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+		return &PasswordInfo{Algorithm: algo, Hash: encoded, Iterations: inner.Iterations, Others: inner}, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errMalformed("hash", err)
+	}
+	size := s.newHash().Size()
+	if len(b) < size || (!s.salted && len(b) != size) || (s.salted && len(b) == size) {
+		return nil, errMalformed("hash", nil)
+	}
+	return &PasswordInfo{
+		Algorithm: algo,
+		Hash:      base64.StdEncoding.EncodeToString(b[:size]),
+		Salt:      string(b[size:]),
+	}, nil
+}
+
+// ldapCryptHasherOf returns the hasher of crypt(3) string of {CRYPT}, nil if
+// it is not supported.
+func ldapCryptHasherOf(encoded string) Hasher {
+	if isBareBcrypt(encoded) {
+		return &bcryptHasher{algo: bcryptAlgo}
+	}
+	if isCrypt(encoded) {
+		return &cryptHasher{}
+	}
+	return nil
+}
+
+// ldapHasher encodes and verifies LDAP userPassword schemes, Algorithm of
+// PasswordInfo is the algorithm of the scheme, such as ldap_ssha512.
+type ldapHasher struct {
+	algo string
+	// cost of bcrypt for {CRYPT}
+	cost int
+}
+
+func (hasher *ldapHasher) Encode(password string) (string, error) {
+	s := ldapSchemes[hasher.algo]
+	if s.newHash == nil {
+		encoded, err := (&bcryptHasher{}).encode(password, bcryptAlgo, hasher.cost)
+		if err != nil {
+			return "", err
+		}
+		return s.scheme + strings.TrimPrefix(encoded, bcryptAlgo+sep), nil
+	}
+
+	var salt []byte
+	if s.salted {
+		var err error
+		if salt, err = generateRandomBytes(ldapSaltLength); err != nil {
+			return "", err
+		}
+	}
+	digest := ldapDigest(s.newHash, password, salt)
+	return s.scheme + base64.StdEncoding.EncodeToString(append(digest, salt...)), nil
+}
+
+func ldapDigest(newHash func() hash.Hash, password string, salt []byte) []byte {
+	h := newHash()
+	h.Write([]byte(password))
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+// Decode decodes LDAP passwords, Others of PasswordInfo is the PasswordInfo
+// of the crypt(3) string for {CRYPT}.
+func (hasher *ldapHasher) Decode(encoded string) (*PasswordInfo, error) {
+	algo, rest := splitLDAP(encoded)
+	if len(algo) == 0 {
+		return nil, errUnknownAlgorithmOf(strings.SplitN(encoded, sep, 2)[0])
+	}
+	return decodeLDAP(algo, rest)
+}
+
+func (hasher *ldapHasher) Verify(password, encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+
+	s := ldapSchemes[pi.Algorithm]
+	if s.newHash == nil {
+		return ldapCryptHasherOf(pi.Hash).Verify(password, pi.Hash)
+	}
+	digest, err := base64.StdEncoding.DecodeString(pi.Hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(ldapDigest(s.newHash, password, []byte(pi.Salt)), digest) == 1
+}
+
+// MustUpdate returns true if scheme differs from the configured one, salt
+// gives less than `saltEntropy` bits, or {CRYPT} is not bcrypt of the cost.
+func (hasher *ldapHasher) MustUpdate(encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+	if pi.Algorithm != hasher.algo {
+		return true
+	}
+	if ldapSchemes[pi.Algorithm].salted && len(pi.Salt)*8 < saltEntropy {
+		return true
+	}
+	if pi.Algorithm != ldapCryptAlgo {
+		return false
+	}
+	return pi.Others.(*PasswordInfo).Algorithm != bcryptAlgo || pi.Iterations < hasher.cost
+}
+
+func (hasher *ldapHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+func newLDAPHasher(opt *HasherOption) (Hasher, error) {
+	if opt.Format != FormatDefault {
+		return nil, ErrUnsupportedFormat
+	}
+	cost := bcrypt.DefaultCost
+	if opt.Iterations > cost {
+		cost = opt.Iterations
+	}
+	return &ldapHasher{algo: opt.Algorithm, cost: cost}, nil
+}
+
+func init() {
+	for _, algo := range []string{ldapSsha512Algo, ldapSsha256Algo, ldapSshaAlgo, ldapShaAlgo, ldapSmd5Algo, ldapMd5Algo, ldapCryptAlgo} {
+		mustRegisterHasher(algo, newLDAPHasher)
+	}
+}
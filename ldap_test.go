@@ -0,0 +1,100 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// generated like `slappasswd -h {SSHA} -s password`
+var ldapPasswords = []struct {
+	algo    string
+	encoded string
+}{
+	{ldapShaAlgo, "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="},
+	{ldapSshaAlgo, "{SSHA}ouUZQtFbhkQrfIJ43qx176Wfj4YBAgME"},
+	{ldapSsha256Algo, "{SSHA256}Zi/fv7HJVr9Eqs8hv38I16KGoQoNMOJOnE+dtTnJuYwoKSorLC0uLzAxMjM0NTY3"},
+	{ldapSsha512Algo, "{SSHA512}Df98gspAQ7mP6AskiFmoMnZ7OcCM8Kji/hYenYWdoXZo0FFRMFUVMCFzlaW7/MCojnMtqYbbnBYNB2Innb+z9igpKissLS4vMDEyMzQ1Njc="},
+	{ldapMd5Algo, "{MD5}X03MO1qnZdYdgyfeuILPmQ=="},
+	{ldapSmd5Algo, "{SMD5}nXuh5+7dhsJMIAfcer5MygECAwQ="},
+	{ldapCryptAlgo, "{CRYPT}$2a$10$dXJ3SW6G7P50lGmMkkmwe.20cQQubK3.HZWzG3YB1tlRy.fqvM/BG"},
+	{ldapSshaAlgo, "{ssha}ouUZQtFbhkQrfIJ43qx176Wfj4YBAgME"},
+}
+
+func TestVerifyLDAP(t *testing.T) {
+	hasher, err := NewHasher(&HasherOption{Algorithm: ldapSsha512Algo, Iterations: 1})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, d := range ldapPasswords {
+		pi, err := hasher.Decode(d.encoded)
+		if err != nil || pi.Algorithm != d.algo {
+			t.Fatalf("Decode(%s) should be %s: %+v %s", d.encoded, d.algo, pi, err)
+		}
+		if !hasher.Verify("password", d.encoded) {
+			t.Errorf("Verify(%s) should be true", d.encoded)
+		}
+		if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+			t.Errorf("Check(wrong, %s) should be ErrMismatch: %s", d.encoded, err)
+		}
+		// other schemes, or salt of 4 bytes
+		if !hasher.MustUpdate(d.encoded) && d.algo != ldapSsha512Algo {
+			t.Errorf("MustUpdate(%s) should be true", d.encoded)
+		}
+
+		if algo, err := Identify(d.encoded); err != nil || algo != d.algo {
+			t.Errorf("Identify(%s) should be %s: %s %s", d.encoded, d.algo, algo, err)
+		}
+		if !Verify("password", d.encoded) {
+			t.Errorf("default PasswordManager should verify %s", d.encoded)
+		}
+	}
+
+	// MD5 of Spring Security is hex
+	if algo, _ := Identify("{MD5}5f4dcc3b5aa765d61d8327deb882cf99"); algo != springAlgo {
+		t.Errorf("{MD5} of hex should be spring: %s", algo)
+	}
+}
+
+func TestEncodeLDAP(t *testing.T) {
+	for _, algo := range []string{ldapSsha512Algo, ldapSsha256Algo, ldapSshaAlgo, ldapShaAlgo, ldapSmd5Algo, ldapMd5Algo, ldapCryptAlgo} {
+		t.Run(algo, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1})
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			encoded, _ := hasher.Encode(password)
+			scheme := ldapSchemes[algo].scheme
+			if !strings.HasPrefix(encoded, scheme) {
+				t.Errorf("encoded should start with %s: %s", scheme, encoded)
+			}
+			if !hasher.Verify(password, encoded) || hasher.MustUpdate(encoded) {
+				t.Error("Verify() should be true without update")
+			}
+		})
+	}
+
+	if _, err := NewHasher(&HasherOption{Algorithm: ldapSsha512Algo, Iterations: 1, Format: FormatPHC}); err != ErrUnsupportedFormat {
+		t.Errorf("LDAP should not support PHC format: %s", err)
+	}
+}
+
+func TestIllegalLDAP(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: ldapSsha512Algo, Iterations: 1})
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"{SSHA384}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", ErrUnknownAlgorithm},
+		{"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9gBAgME", ErrMalformedEncoded},
+		{"{SSHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", ErrMalformedEncoded},
+		{"{SSHA}W6ph5Mm5!", ErrMalformedEncoded},
+		{"{CRYPT}ab01FAX.bQRSU", ErrMalformedEncoded},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LimiterOption Limiter option, 0 means unlimited.
+type LimiterOption struct {
+	// MaxConcurrent: maximum number of concurrent hashing operations
+	MaxConcurrent int `json:"max_concurrent"`
+	// MaxMemory: maximum memory in bytes of concurrent hashing operations,
+	// an operation needing more runs alone
+	MaxMemory uint64 `json:"max_memory"`
+	// MaxWait: maximum time waiting for a slot, `*LimitError` is returned if exceeded
+	MaxWait time.Duration `json:"max_wait"`
+}
+
+// LimitError is returned if a hashing operation waits longer than `LimiterOption.MaxWait`.
+// It matches ErrLimitExceeded by `errors.Is`.
+type LimitError struct {
+	// Wait: time waited
+	Wait time.Duration
+	// QueueDepth: number of waiting operations when the error occurred
+	QueueDepth int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("password hashing limit exceeded: waited %s, %d operations waiting", e.Wait, e.QueueDepth)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+func (e *LimitError) Code() string {
+	return CodeLimitExceeded
+}
+
+// Limiter wraps a Hasher, bounding concurrent Encode and Verify operations
+// and their total memory, which is computed from argon2 memory and scrypt
+// N*r*128. Callers wait in FIFO order.
+//
+// Limiter implements ContextHasher, waiting is cancelled when ctx is done.
+// Verify returns false if no slot is acquired.
+type Limiter struct {
+	hasher Hasher
+	opt    LimiterOption
+
+	mu      sync.Mutex
+	running int
+	memory  uint64
+	queue   []*limiterWaiter
+}
+
+type limiterWaiter struct {
+	memory uint64
+	ready  chan struct{}
+}
+
+// NewLimiter returns a Limiter wrapping hasher.
+func NewLimiter(hasher Hasher, opt LimiterOption) (*Limiter, error) {
+	if hasher == nil {
+		return nil, errIllegalParam("hasher", "cannot be nil")
+	}
+	if opt.MaxConcurrent < 0 {
+		return nil, errIllegalParam("max_concurrent", "should not be negative")
+	}
+	if opt.MaxWait < 0 {
+		return nil, errIllegalParam("max_wait", "should not be negative")
+	}
+	return &Limiter{hasher: hasher, opt: opt}, nil
+}
+
+// QueueDepth returns the number of operations waiting for a slot.
+func (l *Limiter) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queue)
+}
+
+func (l *Limiter) Encode(password string) (string, error) {
+	return l.EncodeContext(context.Background(), password)
+}
+
+func (l *Limiter) EncodeContext(ctx context.Context, password string) (string, error) {
+	memory := memoryCostOf(l.hasher, "")
+	if err := l.acquire(ctx, memory); err != nil {
+		return "", err
+	}
+	defer l.release(memory)
+	return l.hasher.Encode(password)
+}
+
+func (l *Limiter) Decode(encoded string) (*PasswordInfo, error) {
+	return l.hasher.Decode(encoded)
+}
+
+func (l *Limiter) Verify(password, encoded string) bool {
+	ok, _ := l.VerifyContext(context.Background(), password, encoded)
+	return ok
+}
+
+func (l *Limiter) VerifyContext(ctx context.Context, password, encoded string) (bool, error) {
+	memory := memoryCostOf(l.hasher, encoded)
+	if err := l.acquire(ctx, memory); err != nil {
+		return false, err
+	}
+	defer l.release(memory)
+	return l.hasher.Verify(password, encoded), nil
+}
+
+// Check returns the error of waiting, such as *LimitError, or the error of
+// the wrapped hasher.
+func (l *Limiter) Check(password, encoded string) error {
+	memory := memoryCostOf(l.hasher, encoded)
+	if err := l.acquire(context.Background(), memory); err != nil {
+		return err
+	}
+	defer l.release(memory)
+	return CheckWith(l.hasher, password, encoded)
+}
+
+func (l *Limiter) verifyUpgrade(password, encoded string) (bool, bool) {
+	memory := memoryCostOf(l.hasher, encoded)
+	if err := l.acquire(context.Background(), memory); err != nil {
+		return false, false
+	}
+	defer l.release(memory)
+	return verifyUpgrade(l.hasher, password, encoded)
+}
+
+func (l *Limiter) MustUpdate(encoded string) bool {
+	return l.hasher.MustUpdate(encoded)
+}
+
+func (l *Limiter) Harden(password, encoded string) (string, error) {
+	return harden(l, password, encoded)
+}
+
+func (l *Limiter) acquire(ctx context.Context, memory uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.opt.MaxMemory > 0 && memory > l.opt.MaxMemory {
+		memory = l.opt.MaxMemory
+	}
+
+	l.mu.Lock()
+	if len(l.queue) == 0 && l.fits(memory) {
+		l.take(memory)
+		l.mu.Unlock()
+		return nil
+	}
+	w := &limiterWaiter{memory: memory, ready: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	l.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if l.opt.MaxWait > 0 {
+		timer := time.NewTimer(l.opt.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = &LimitError{Wait: time.Since(start)}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// the slot was granted meanwhile
+		l.releaseLocked(memory)
+	default:
+		l.remove(w)
+		l.wake()
+	}
+	if e, ok := err.(*LimitError); ok {
+		e.QueueDepth = len(l.queue)
+	}
+	return err
+}
+
+func (l *Limiter) release(memory uint64) {
+	if l.opt.MaxMemory > 0 && memory > l.opt.MaxMemory {
+		memory = l.opt.MaxMemory
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked(memory)
+}
+
+func (l *Limiter) releaseLocked(memory uint64) {
+	l.running--
+	l.memory -= memory
+	l.wake()
+}
+
+func (l *Limiter) fits(memory uint64) bool {
+	if l.opt.MaxConcurrent > 0 && l.running >= l.opt.MaxConcurrent {
+		return false
+	}
+	return l.opt.MaxMemory == 0 || l.memory+memory <= l.opt.MaxMemory
+}
+
+func (l *Limiter) take(memory uint64) {
+	l.running++
+	l.memory += memory
+}
+
+// wake grants slots to waiters in order, until the first one which does not fit.
+func (l *Limiter) wake() {
+	for len(l.queue) > 0 && l.fits(l.queue[0].memory) {
+		w := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		l.take(w.memory)
+		close(w.ready)
+	}
+}
+
+func (l *Limiter) remove(w *limiterWaiter) {
+	for i, v := range l.queue {
+		if v == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// memoryCostOf returns memory in bytes needed to verify encoded with hasher,
+// or to encode a password if encoded is blank.
+func memoryCostOf(hasher Hasher, encoded string) uint64 {
+	if len(encoded) > 0 {
+		pi, err := hasher.Decode(encoded)
+		if err != nil {
+			return 0
+		}
+		return memoryCostOfInfo(pi)
+	}
+
+	switch h := hasher.(type) {
+	case *argon2Hasher:
+		return memoryCostOfInfo(&PasswordInfo{Others: h.params})
+	case *scryptHasher:
+		return memoryCostOfInfo(&PasswordInfo{Others: h.params})
+	case *pepperHasher:
+		return memoryCostOf(h.hasher, "")
+	case *wrappedHasher:
+		return memoryCostOf(h.outer, "")
+	case *PasswordManager:
+		return memoryCostOf(h.hashers[h.preferred], "")
+	case *Limiter:
+		return memoryCostOf(h.hasher, "")
+	}
+	return 0
+}
+
+func memoryCostOfInfo(pi *PasswordInfo) uint64 {
+	switch p := pi.Others.(type) {
+	case *Argon2Params:
+		return uint64(p.Memory) * 1024
+	case *ScryptParams:
+		return 128 * uint64(p.N) * uint64(p.R)
+	case *WrappedInfo:
+		return memoryCostOfInfo(p.Outer)
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package password
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// blockingHasher blocks Verify until release is closed.
+type blockingHasher struct {
+	Hasher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHasher) Verify(password, encoded string) bool {
+	h.started <- struct{}{}
+	<-h.release
+	return h.Hasher.Verify(password, encoded)
+}
+
+func newBlockingHasher(t *testing.T) *blockingHasher {
+	hasher, err := NewHasher(&HasherOption{
+		Algorithm:  argon2Algo,
+		Iterations: 1,
+		Params:     &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &blockingHasher{Hasher: hasher, started: make(chan struct{}, 8), release: make(chan struct{})}
+}
+
+func TestLimiter(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000})
+	l, err := NewLimiter(hasher, LimiterOption{MaxConcurrent: 2})
+	if err != nil {
+		t.Fatalf("NewLimiter should be ok: %s", err)
+	}
+
+	encoded, err := l.Encode(password)
+	if err != nil {
+		t.Fatalf("Encode should be ok: %s", err)
+	}
+	if !l.Verify(password, encoded) || l.Verify("wrong", encoded) {
+		t.Error("Verify should be passed to hasher")
+	}
+	if _, err = l.Decode(encoded); err != nil || l.MustUpdate(encoded) {
+		t.Errorf("Decode and MustUpdate should be passed to hasher: %s", err)
+	}
+	if ok, err := VerifyContext(context.Background(), l, password, encoded); !ok || err != nil {
+		t.Errorf("VerifyContext should be true: %s", err)
+	}
+
+	for _, opt := range []LimiterOption{{MaxConcurrent: -1}, {MaxWait: -time.Second}} {
+		if _, err = NewLimiter(hasher, opt); err == nil {
+			t.Errorf("NewLimiter(%+v) should be error", opt)
+		}
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	h := newBlockingHasher(t)
+	l, _ := NewLimiter(h, LimiterOption{MaxConcurrent: 1, MaxWait: 50 * time.Millisecond})
+	encoded, _ := h.Encode(password)
+
+	done := make(chan bool)
+	go func() {
+		done <- l.Verify(password, encoded)
+	}()
+	<-h.started
+
+	_, err := l.VerifyContext(context.Background(), password, encoded)
+	if e, ok := err.(*LimitError); !ok || e.Wait < 50*time.Millisecond {
+		t.Errorf("VerifyContext should be *LimitError: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error)
+	go func() {
+		_, err := l.EncodeContext(ctx, password)
+		waiting <- err
+	}()
+	for l.QueueDepth() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err = <-waiting; err != context.Canceled {
+		t.Errorf("EncodeContext should be canceled: %s", err)
+	}
+	if l.QueueDepth() != 0 {
+		t.Errorf("QueueDepth should be 0: %d", l.QueueDepth())
+	}
+
+	close(h.release)
+	if !<-done {
+		t.Error("Verify should be true")
+	}
+	if ok, err := l.VerifyContext(context.Background(), password, encoded); !ok || err != nil {
+		t.Errorf("VerifyContext should be ok after release: %s", err)
+	}
+}
+
+func TestLimiterMemory(t *testing.T) {
+	h := newBlockingHasher(t)
+	// 1.5 MiB fits only one argon2 operation of 1 MiB
+	l, _ := NewLimiter(h, LimiterOption{MaxMemory: 1536 * 1024})
+	encoded, _ := h.Encode(password)
+
+	go l.Verify(password, encoded)
+	<-h.started
+
+	results := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		for l.QueueDepth() != i-1 {
+			time.Sleep(time.Millisecond)
+		}
+		go func(i int) {
+			l.Verify(password, encoded)
+			results <- i
+		}(i)
+	}
+	for l.QueueDepth() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(h.release)
+	for i := 1; i <= 2; i++ {
+		<-h.started
+		if r := <-results; r != i {
+			t.Errorf("waiters should be served in order: %d", r)
+		}
+	}
+}
+
+func TestMemoryCost(t *testing.T) {
+	argon2, _ := NewHasher(&HasherOption{Algorithm: argon2Algo, Iterations: 1, Secret: "pepper"})
+	scrypt, _ := NewHasher(&HasherOption{Algorithm: scryptAlgo, Iterations: 1})
+	bcrypt, _ := NewHasher(&HasherOption{Algorithm: bcryptAlgo, Iterations: 10})
+	m, _ := NewPasswordManager(&HasherOption{Algorithm: scryptAlgo, Iterations: 1})
+
+	data := []struct {
+		hasher   Hasher
+		expected uint64
+	}{
+		{argon2, 64 << 20},
+		{scrypt, 16 << 20},
+		{bcrypt, 0},
+		{m, 16 << 20},
+	}
+	for _, d := range data {
+		if cost := memoryCostOf(d.hasher, ""); cost != d.expected {
+			t.Errorf("memory cost should be %d: %d", d.expected, cost)
+		}
+	}
+
+	encoded := "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+	if cost := memoryCostOf(scrypt, encoded); cost != 1<<20 {
+		t.Errorf("memory cost of encoded should be %d: %d", 1<<20, cost)
+	}
+}
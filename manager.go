@@ -0,0 +1,273 @@
+package password
+
+import (
+	"strings"
+	"sync"
+)
+
+// Errors of NewPasswordManager.
+var (
+	ErrNoHasherOption     = newError(CodeNoHasherOption, "at least one HasherOption should be provided")
+	ErrDuplicateAlgorithm = newError(CodeDuplicateAlgorithm, "duplicate algorithm")
+)
+
+// PasswordManager manages several hashers, like django's `PASSWORD_HASHERS`.
+//
+// The first hasher is the preferred one: `Encode` always uses it, and
+// `MustUpdate` reports true for passwords encoded by the others.
+// `Decode` and `Verify` dispatch by the algorithm prefix of the encoded password.
+type PasswordManager struct {
+	preferred  string
+	algorithms []string
+	hashers    map[string]Hasher
+}
+
+// NewPasswordManager returns a PasswordManager with hashers made from opts.
+// The first option is the preferred one.
+func NewPasswordManager(opts ...*HasherOption) (*PasswordManager, error) {
+	if len(opts) == 0 {
+		return nil, ErrNoHasherOption
+	}
+
+	m := &PasswordManager{
+		algorithms: make([]string, 0, len(opts)),
+		hashers:    make(map[string]Hasher, len(opts)),
+	}
+	for _, opt := range opts {
+		hasher, err := NewHasher(opt)
+		if err != nil {
+			return nil, err
+		}
+		if err = m.add(opt.Algorithm, hasher); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *PasswordManager) add(algorithm string, hasher Hasher) error {
+	if _, ok := m.hashers[algorithm]; ok {
+		return ErrDuplicateAlgorithm
+	}
+	if len(m.algorithms) == 0 {
+		m.preferred = algorithm
+	}
+	m.algorithms = append(m.algorithms, algorithm)
+	m.hashers[algorithm] = hasher
+	return nil
+}
+
+// Algorithms returns the managed algorithms, the preferred one first.
+func (m *PasswordManager) Algorithms() []string {
+	algorithms := make([]string, len(m.algorithms))
+	copy(algorithms, m.algorithms)
+	return algorithms
+}
+
+// Identify returns the algorithm of the encoded password.
+func (m *PasswordManager) Identify(encoded string) (string, error) {
+	if _, _, err := splitPepper(encoded); err != nil {
+		return "", err
+	}
+	algorithm := algorithmOf(encoded)
+	if _, ok := m.hashers[algorithm]; !ok {
+		return "", errUnknownAlgorithmOf(algorithm)
+	}
+	return algorithm, nil
+}
+
+func (m *PasswordManager) hasherOf(encoded string) (Hasher, error) {
+	algorithm, err := m.Identify(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return m.hashers[algorithm], nil
+}
+
+// Encode encodes password with the preferred hasher.
+func (m *PasswordManager) Encode(password string) (string, error) {
+	return m.hashers[m.preferred].Encode(password)
+}
+
+func (m *PasswordManager) Decode(encoded string) (*PasswordInfo, error) {
+	hasher, err := m.hasherOf(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return hasher.Decode(encoded)
+}
+
+func (m *PasswordManager) Verify(password, encoded string) bool {
+	hasher, err := m.hasherOf(encoded)
+	if err != nil {
+		return false
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// Check verifies password with the hasher of encoded, see `CheckWith`.
+func (m *PasswordManager) Check(password, encoded string) error {
+	hasher, err := m.hasherOf(encoded)
+	if err != nil {
+		return err
+	}
+	return CheckWith(hasher, password, encoded)
+}
+
+// MustUpdate returns true if encoded was not made by the preferred hasher,
+// or the preferred hasher wants to update it.
+func (m *PasswordManager) MustUpdate(encoded string) bool {
+	algorithm, err := m.Identify(encoded)
+	if err != nil {
+		return false
+	}
+	if algorithm != m.preferred {
+		return true
+	}
+	return m.hashers[algorithm].MustUpdate(encoded)
+}
+
+func (m *PasswordManager) verifyUpgrade(password, encoded string) (bool, bool) {
+	algorithm, err := m.Identify(encoded)
+	if err != nil {
+		return false, false
+	}
+	ok, mustUpdate := verifyUpgrade(m.hashers[algorithm], password, encoded)
+	return ok, ok && (mustUpdate || algorithm != m.preferred)
+}
+
+// Harden re-encodes password with the preferred hasher if `MustUpdate` reports true.
+func (m *PasswordManager) Harden(password, encoded string) (string, error) {
+	return harden(m, password, encoded)
+}
+
+// algorithmOf returns the algorithm of encoded, which is the prefix of encoded,
+// or mapped from the identifier of PHC, modular crypt string, Werkzeug method,
+// passlib prefix, LDAP scheme, or `{id}` of Spring Security. ASP.NET Core
+// Identity passwords have no prefix.
+// Pepper prefix is skipped.
+func algorithmOf(encoded string) string {
+	_, encoded, _ = splitPepper(encoded)
+	if isDjangoArgon2(encoded) {
+		encoded = encoded[len(djangoArgon2Prefix):]
+	}
+	if isWerkzeug(encoded) {
+		return werkzeugAlgorithmOf(encoded)
+	}
+	if prefix := passlibPrefixOf(encoded); len(prefix) > 0 {
+		return passlibIdentifiers[prefix]
+	}
+	if algo := ldapAlgorithmOf(encoded); len(algo) > 0 {
+		return algo
+	}
+	if isSpring(encoded) {
+		return springAlgo
+	}
+	if isAspNetIdentity(encoded) {
+		return aspNetIdentityAlgo
+	}
+	if isPHC(encoded) {
+		id := strings.SplitN(encoded[len(sep):], sep, 2)[0]
+		if algo, ok := phcIdentifiers[id]; ok {
+			return algo
+		}
+		if algo, ok := cryptIdentifiers[id]; ok {
+			return algo
+		}
+		return bcryptIdentifiers[id]
+	}
+	return strings.SplitN(encoded, sep, 2)[0]
+}
+
+// defaultHasherOptions are the options of the default PasswordManager, which
+// verifies passwords of all built-in algorithms. Costs are read from encoded
+// passwords, so Iterations only matter to `Encode` and `MustUpdate`.
+var defaultHasherOptions = []*HasherOption{
+	{Algorithm: argon2Algo, Iterations: 1},
+	{Algorithm: argon2iAlgo, Iterations: 1},
+	{Algorithm: argon2dAlgo, Iterations: 1},
+	{Algorithm: pbkdf2Sha256Algo, Iterations: 1},
+	{Algorithm: pbkdf2Sha1Algo, Iterations: 1},
+	{Algorithm: pbkdf2Sha512Algo, Iterations: 1},
+	{Algorithm: bcryptSha256Algo, Iterations: 1},
+	{Algorithm: bcryptAlgo, Iterations: 1},
+	{Algorithm: scryptAlgo, Iterations: 1},
+	{Algorithm: sha1Algo, Iterations: 1},
+	{Algorithm: md5Algo, Iterations: 1},
+	{Algorithm: unsaltedMd5Algo, Iterations: 1},
+	{Algorithm: aspNetIdentityAlgo, Iterations: 1},
+	{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: argon2Algo, Iterations: 1}},
+	{Algorithm: ldapSsha512Algo, Iterations: 1},
+	{Algorithm: ldapSsha256Algo, Iterations: 1},
+	{Algorithm: ldapSshaAlgo, Iterations: 1},
+	{Algorithm: ldapShaAlgo, Iterations: 1},
+	{Algorithm: ldapSmd5Algo, Iterations: 1},
+	{Algorithm: ldapMd5Algo, Iterations: 1},
+	{Algorithm: ldapCryptAlgo, Iterations: 1},
+	{Algorithm: sha512CryptAlgo, Iterations: 1},
+	{Algorithm: sha256CryptAlgo, Iterations: 1},
+	{Algorithm: md5CryptAlgo, Iterations: 1},
+}
+
+var (
+	defaultPasswordManager     *PasswordManager
+	defaultPasswordManagerOnce sync.Once
+)
+
+// newDefaultPasswordManager makes the default PasswordManager by the
+// registered factories, panic if errors occurred.
+func newDefaultPasswordManager() *PasswordManager {
+	m, err := NewPasswordManager(defaultHasherOptions...)
+	if err != nil {
+		panic("default PasswordManager: " + err.Error())
+	}
+	// wrapped passwords are verified by all algorithms of the default PasswordManager
+	if err = m.add(wrappedAlgo, &wrappedHasher{}); err != nil {
+		panic("default PasswordManager: " + err.Error())
+	}
+	return m
+}
+
+// defaultManager returns the default PasswordManager. It is made on first
+// use, because built-in hashers are registered by init functions.
+func defaultManager() *PasswordManager {
+	defaultPasswordManagerOnce.Do(func() {
+		if defaultPasswordManager == nil {
+			defaultPasswordManager = newDefaultPasswordManager()
+		}
+	})
+	return defaultPasswordManager
+}
+
+// SetDefaultPasswordManager replaces the PasswordManager used by `Verify` and `Identify`.
+//
+// It is not goroutine-safe, call it during initialization.
+func SetDefaultPasswordManager(m *PasswordManager) {
+	if m != nil {
+		defaultPasswordManagerOnce.Do(func() {})
+		defaultPasswordManager = m
+	}
+}
+
+// Verify verifies password with the default PasswordManager, which supports
+// all built-in algorithms.
+func Verify(password, encoded string) bool {
+	return defaultManager().Verify(password, encoded)
+}
+
+// Check verifies password with the default PasswordManager like `Verify`,
+// and returns why it fails, see `CheckWith`.
+func Check(password, encoded string) error {
+	return defaultManager().Check(password, encoded)
+}
+
+// Identify returns the algorithm of encoded with the default PasswordManager.
+func Identify(encoded string) (string, error) {
+	return defaultManager().Identify(encoded)
+}
+
+// VerifyAndUpgrade verifies password, and re-encodes it with the preferred
+// hasher if needed, see `VerifyAndUpgrade`.
+func (m *PasswordManager) VerifyAndUpgrade(password, encoded string, persist func(newEncoded string) error) (bool, string, error) {
+	return VerifyAndUpgrade(m, password, encoded, persist)
+}
@@ -0,0 +1,134 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewPasswordManager(t *testing.T) {
+	if _, err := NewPasswordManager(); err != ErrNoHasherOption {
+		t.Errorf("NewPasswordManager() should be ErrNoHasherOption: %s", err)
+	}
+
+	opt := &HasherOption{Algorithm: md5Algo, Salt: "salt", Iterations: 1}
+	if _, err := NewPasswordManager(opt, opt); err != ErrDuplicateAlgorithm {
+		t.Errorf("NewPasswordManager(opt, opt) should be ErrDuplicateAlgorithm: %s", err)
+	}
+
+	if _, err := NewPasswordManager(&HasherOption{Algorithm: "non-algo"}); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("NewPasswordManager(non-algo) should be ErrUnknownAlgorithm: %s", err)
+	}
+}
+
+func TestPasswordManager(t *testing.T) {
+	md5Opt := &HasherOption{Algorithm: md5Algo, Salt: "saltsaltsaltsalt", Iterations: 1}
+	pbkdf2Opt := &HasherOption{Algorithm: pbkdf2Sha256Algo, Salt: "saltsaltsaltsalt", Iterations: 1000}
+	argon2Opt := &HasherOption{Algorithm: argon2Algo, Iterations: 1}
+
+	md5Hasher, _ := NewHasher(md5Opt)
+	legacy, _ := md5Hasher.Encode(password)
+
+	m, err := NewPasswordManager(argon2Opt, pbkdf2Opt, md5Opt)
+	if err != nil {
+		t.Fatalf("NewPasswordManager should be ok: %s", err)
+	}
+
+	algorithms := m.Algorithms()
+	if len(algorithms) != 3 || algorithms[0] != argon2Algo {
+		t.Errorf("wrong algorithms: %v", algorithms)
+	}
+
+	encoded, err := m.Encode(password)
+	if err != nil {
+		t.Errorf("Encode(password) should be ok: %s", err)
+	}
+	if algo, _ := m.Identify(encoded); algo != argon2Algo {
+		t.Errorf("Encode should use preferred hasher, got %s", algo)
+	}
+
+	if !m.Verify(password, encoded) || !m.Verify(password, legacy) {
+		t.Error("Verify should be true")
+	}
+	if m.Verify("wrong", legacy) {
+		t.Error("Verify(wrong, legacy) should be false")
+	}
+
+	pi, err := m.Decode(legacy)
+	if err != nil {
+		t.Errorf("Decode(legacy) should be ok: %s", err)
+	} else if pi.Algorithm != md5Algo {
+		t.Errorf("Decode(legacy) algorithm should be %s: %s", md5Algo, pi.Algorithm)
+	}
+
+	if m.MustUpdate(encoded) {
+		t.Error("MustUpdate(encoded) should be false")
+	}
+	if !m.MustUpdate(legacy) {
+		t.Error("MustUpdate(legacy) should be true because of non-preferred algorithm")
+	}
+
+	sha1Encoded := "sha1$salt$59b3e8d637cf97edbe2384cf59cb7453dfe30789"
+	if _, err = m.Identify(sha1Encoded); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Identify(sha1Encoded) should be ErrUnknownAlgorithm: %s", err)
+	}
+	if m.Verify(password, sha1Encoded) {
+		t.Error("Verify(sha1Encoded) should be false")
+	}
+}
+
+func TestDefaultPasswordManager(t *testing.T) {
+	opts := []*HasherOption{
+		{Algorithm: pbkdf2Sha1Algo, Salt: "salt", Iterations: 100},
+		{Algorithm: bcryptSha256Algo, Iterations: 4},
+		{Algorithm: scryptAlgo, Salt: "salt", Iterations: 1},
+		{Algorithm: sha1Algo, Salt: "salt", Iterations: 1},
+		{Algorithm: unsaltedMd5Algo, Iterations: 1},
+	}
+	for _, opt := range opts {
+		hasher, _ := NewHasher(opt)
+		encoded, _ := hasher.Encode(password)
+
+		algo, err := Identify(encoded)
+		if err != nil || algo != opt.Algorithm {
+			t.Errorf("Identify(%s) should be %s: %s", encoded, opt.Algorithm, err)
+		}
+		if !Verify(password, encoded) {
+			t.Errorf("Verify(%s) should be true", encoded)
+		}
+	}
+
+	if _, err := Identify("non-algo$salt$hash"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Identify(non-algo) should be ErrUnknownAlgorithm: %s", err)
+	}
+}
+
+func TestDefaultPasswordManagerAlgorithms(t *testing.T) {
+	algorithms := map[string]bool{}
+	for _, algorithm := range defaultManager().Algorithms() {
+		algorithms[algorithm] = true
+	}
+	for _, algorithm := range RegisteredAlgorithms() {
+		if !algorithms[algorithm] {
+			t.Errorf("default PasswordManager should verify %s", algorithm)
+		}
+	}
+
+	for _, opt := range defaultHasherOptions {
+		hasher, err := NewHasher(opt)
+		if err != nil {
+			t.Fatalf("NewHasher(%s) should be ok: %s", opt.Algorithm, err)
+		}
+		encoded, _ := hasher.Encode(password)
+		if algo, err := Identify(encoded); err != nil || algo != opt.Algorithm {
+			t.Errorf("Identify(%s) should be %s: %s %s", encoded, opt.Algorithm, algo, err)
+		}
+		if !Verify(password, encoded) || Verify("wrong", encoded) {
+			t.Errorf("Verify(%s) should be true only for the password", encoded)
+		}
+	}
+
+	// md5(salt + password)
+	if !Verify("password", "md5$salt$67a1e09bb1f83f5007dc119c14d663aa") {
+		t.Error("default PasswordManager should verify salted md5")
+	}
+}
@@ -2,27 +2,34 @@ package password
 
 import (
 	"crypto/md5" // #nosec
+	"crypto/subtle"
 	"encoding/hex"
 	"strings"
 )
 
 type md5Hasher struct {
-	salt string
+	algo string
+	salt saltOption
 }
 
-func (hasher *md5Hasher) Algorithm() string {
-	if len(hasher.salt) > 0 {
-		return md5Algo
-	} else {
-		return unsaltedMd5Algo
+func (hasher *md5Hasher) Encode(password string) (string, error) {
+	if hasher.algo == unsaltedMd5Algo {
+		return hasher.encode(unsaltedMd5Algo, password, ""), nil
+	}
+
+	salt, err := hasher.salt.generate()
+	if err != nil {
+		return "", err
 	}
+	return hasher.encode(md5Algo, password, salt), nil
 }
 
-func (hasher *md5Hasher) Encode(password string) (string, error) {
-	return hasher.encode(password, hasher.salt), nil
+func (hasher *md5Hasher) encode(algo, password, salt string) string {
+	parts := []string{algo, salt, hex.EncodeToString(hasher.digest(password, salt))}
+	return strings.Join(parts, sep)
 }
 
-func (hasher *md5Hasher) encode(password, salt string) string {
+func (hasher *md5Hasher) digest(password, salt string) []byte {
 	h := md5.New() // #nosec
 
 	// to support `unsalted_md5`
@@ -30,14 +37,19 @@ func (hasher *md5Hasher) encode(password, salt string) string {
 		h.Write([]byte(salt))
 	}
 	h.Write([]byte(password))
-	parts := []string{hasher.Algorithm(), salt, hex.EncodeToString(h.Sum(nil))}
-	return strings.Join(parts, sep)
+	return h.Sum(nil)
 }
 
 func (hasher *md5Hasher) Decode(encoded string) (*PasswordInfo, error) {
 	parts := strings.SplitN(encoded, sep, 3)
 	if parts[0] != md5Algo && parts[0] != unsaltedMd5Algo {
-		return nil, errUnknownAlgorithm
+		return nil, errUnknownAlgorithmOf(parts[0])
+	}
+	if len(parts) != 3 {
+		return nil, ErrMalformedEncoded
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return nil, errMalformed("hash", err)
 	}
 
 	return &PasswordInfo{
@@ -53,7 +65,11 @@ func (hasher *md5Hasher) Verify(password, encoded string) bool {
 		return false
 	}
 
-	return hasher.encode(password, pi.Salt) == encoded
+	hash, err := hex.DecodeString(pi.Hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hasher.digest(password, pi.Salt), hash) == 1
 }
 
 func (hasher *md5Hasher) MustUpdate(encoded string) bool {
@@ -62,22 +78,29 @@ func (hasher *md5Hasher) MustUpdate(encoded string) bool {
 		return false
 	}
 
+	if pi.Algorithm != hasher.algo {
+		return true
+	}
 	if pi.Algorithm == unsaltedMd5Algo {
 		return false
 	}
 
-	ret := mustUpdateSalt(pi.Salt, saltEntropy)
-	if ret {
-		return true
-	}
-
-	return len(pi.Salt) < len(hasher.salt)
+	return hasher.salt.mustUpdate(pi.Salt)
 }
 
 func (hasher *md5Hasher) Harden(password, encoded string) (string, error) {
-	return encoded, nil
+	return harden(hasher, password, encoded)
 }
 
 func newMD5Hasher(opt *HasherOption) (Hasher, error) {
-	return &md5Hasher{salt: opt.Salt}, nil
+	if defaultFormatOf(opt.Format) != FormatDefault || (opt.Format == FormatDjango && opt.Algorithm == unsaltedMd5Algo) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	return &md5Hasher{algo: opt.Algorithm, salt: newSaltOption(opt)}, nil
+}
+
+func init() {
+	mustRegisterHasher(md5Algo, newMD5Hasher)
+	mustRegisterHasher(unsaltedMd5Algo, newMD5Hasher)
 }
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// Passlib format of pbkdf2 hashes of `passlib.hash`:
+//
+//	$pbkdf2-sha256$<rounds>$<ab64 salt>$<ab64 hash>
+//	{PBKDF2-SHA256}<rounds>$<ab64 salt>$<ab64 hash>
+//
+// The latter is ldap_pbkdf2_sha256. Rounds are not prefixed by `i=`, which
+// tells them from PHC strings.
+
+// passlibIdentifiers maps prefixes of passlib hashes to algorithms.
+var passlibIdentifiers = map[string]string{
+	"$pbkdf2$":        pbkdf2Sha1Algo,
+	"$pbkdf2-sha256$": pbkdf2Sha256Algo,
+	"$pbkdf2-sha512$": pbkdf2Sha512Algo,
+	"{PBKDF2}":        pbkdf2Sha1Algo,
+	"{PBKDF2-SHA256}": pbkdf2Sha256Algo,
+	"{PBKDF2-SHA512}": pbkdf2Sha512Algo,
+}
+
+// passlibEncoding is adapted base64 of passlib, which uses `.` instead of `+`
+// without padding.
+var passlibEncoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").
+	WithPadding(base64.NoPadding)
+
+type passlibHash struct {
+	algo   string
+	rounds int
+	salt   []byte
+	hash   []byte
+}
+
+// passlibPrefixOf returns the prefix of passlib encoded password, blank if
+// encoded is not a passlib hash.
+func passlibPrefixOf(encoded string) string {
+	for prefix := range passlibIdentifiers {
+		if !strings.HasPrefix(encoded, prefix) {
+			continue
+		}
+		rounds := strings.SplitN(encoded[len(prefix):], sep, 2)[0]
+		if _, err := strconv.Atoi(rounds); err == nil {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func isPasslib(encoded string) bool {
+	return len(passlibPrefixOf(encoded)) > 0
+}
+
+// passlibPrefixOfAlgorithm returns the modular crypt prefix of algorithm.
+func passlibPrefixOfAlgorithm(algorithm string) string {
+	for prefix, algo := range passlibIdentifiers {
+		if algo == algorithm && strings.HasPrefix(prefix, sep) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func parsePasslib(encoded string) (*passlibHash, error) {
+	prefix := passlibPrefixOf(encoded)
+	if len(prefix) == 0 {
+		return nil, ErrMalformedEncoded
+	}
+	parts := strings.Split(encoded[len(prefix):], sep)
+	if len(parts) != 3 {
+		return nil, ErrMalformedEncoded
+	}
+	rounds, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, errMalformed("rounds", err)
+	}
+	if rounds < 1 {
+		return nil, errIllegalParam("rounds", "should be at least 1")
+	}
+	salt, err := passlibEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errMalformed("salt", err)
+	}
+	hash, err := passlibEncoding.DecodeString(parts[2])
+	if err != nil || len(hash) == 0 {
+		return nil, errMalformed("hash", err)
+	}
+	return &passlibHash{algo: passlibIdentifiers[prefix], rounds: rounds, salt: salt, hash: hash}, nil
+}
+
+// formatPasslib returns passlib encoded password with modular crypt prefix.
+func formatPasslib(algorithm string, rounds int, salt, hash []byte) string {
+	return passlibPrefixOfAlgorithm(algorithm) + strings.Join([]string{
+		strconv.Itoa(rounds),
+		passlibEncoding.EncodeToString(salt),
+		passlibEncoding.EncodeToString(hash),
+	}, sep)
+}
@@ -0,0 +1,102 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// generated like `passlib.hash.pbkdf2_sha256.hash("password")`
+var passlibPasswords = []struct {
+	algo    string
+	encoded string
+}{
+	{pbkdf2Sha1Algo, "$pbkdf2$131000$o/HJ4HstT1i.Ef8.mgx9Yg$YebnOzIFQedsMq9lWIoZD3zFzyk"},
+	{pbkdf2Sha256Algo, "$pbkdf2-sha256$29000$o/HJ4HstT1i.Ef8.mgx9Yg$4qk8SsoeTHGTgu7Dd7sWqrXKlBpRwcOCZBL2kBX.qBw"},
+	{pbkdf2Sha512Algo, "$pbkdf2-sha512$25000$o/HJ4HstT1i.Ef8.mgx9Yg$Pl0Ii8QwgnGnydDfnO.noI7uvkOAKGEKeKp21u5KRN0RbceBRVXnzySrFnJQDhS40iSWSZj4626GAxrCYKSaKg"},
+	{pbkdf2Sha256Algo, "{PBKDF2-SHA256}29000$o/HJ4HstT1i.Ef8.mgx9Yg$4qk8SsoeTHGTgu7Dd7sWqrXKlBpRwcOCZBL2kBX.qBw"},
+}
+
+func TestVerifyPasslib(t *testing.T) {
+	for _, d := range passlibPasswords {
+		t.Run(d.encoded[:strings.LastIndex(d.encoded, sep)], func(t *testing.T) {
+			hasher, _ := NewHasher(&HasherOption{Algorithm: d.algo, Iterations: 1})
+			pi, err := hasher.Decode(d.encoded)
+			if err != nil || pi.Algorithm != d.algo || len(pi.Salt) != 16 {
+				t.Fatalf("Decode(passlib) should be ok: %+v %s", pi, err)
+			}
+			if !hasher.Verify("password", d.encoded) {
+				t.Error("Verify(passlib) should be true")
+			}
+			if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+				t.Errorf("Check(wrong) should be ErrMismatch: %s", err)
+			}
+			if !hasher.MustUpdate(d.encoded) {
+				t.Error("should update passlib to the default format")
+			}
+
+			if algo, err := Identify(d.encoded); err != nil || algo != d.algo {
+				t.Errorf("Identify(passlib) should be %s: %s %s", d.algo, algo, err)
+			}
+			if !Verify("password", d.encoded) {
+				t.Error("default PasswordManager should verify passlib")
+			}
+		})
+	}
+}
+
+func TestEncodePasslib(t *testing.T) {
+	data := []struct {
+		algo   string
+		prefix string
+	}{
+		{pbkdf2Sha1Algo, "$pbkdf2$1000$"},
+		{pbkdf2Sha256Algo, "$pbkdf2-sha256$1000$"},
+		{pbkdf2Sha512Algo, "$pbkdf2-sha512$1000$"},
+	}
+	for _, d := range data {
+		t.Run(d.algo, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{Algorithm: d.algo, Iterations: 1000, Format: FormatPasslib})
+			if err != nil {
+				t.Fatalf("NewHasher should support passlib format: %s", err)
+			}
+			encoded, _ := hasher.Encode(password)
+			if !strings.HasPrefix(encoded, d.prefix) {
+				t.Errorf("encoded should start with %s: %s", d.prefix, encoded)
+			}
+			if !hasher.Verify(password, encoded) || hasher.MustUpdate(encoded) {
+				t.Error("Verify() should be true without update")
+			}
+		})
+	}
+
+	for _, algo := range []string{argon2Algo, scryptAlgo, bcryptAlgo, md5Algo, sha1Algo} {
+		if _, err := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1, Format: FormatPasslib}); err != ErrUnsupportedFormat {
+			t.Errorf("%s should not support passlib format: %s", algo, err)
+		}
+	}
+}
+
+func TestIllegalPasslib(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1})
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"$pbkdf2-sha256$29000$o/HJ4HstT1i.Ef8.mgx9Yg", ErrMalformedEncoded},
+		{"$pbkdf2-sha256$0$o/HJ4HstT1i.Ef8.mgx9Yg$4qk8", ErrIllegalParam},
+		{"$pbkdf2-sha256$29000$o+HJ4HstT1i$4qk8", ErrMalformedEncoded},
+		{"$pbkdf2-sha256$29000$o/HJ4HstT1i.Ef8.mgx9Yg$", ErrMalformedEncoded},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+
+	// PHC strings are not passlib hashes
+	phc := "$pbkdf2-sha256$i=1000$c2FsdA$4qk8SsoeTHGTgu7Dd7sWqrXKlBpRwcOCZBL2kBX.qBw"
+	if isPasslib(phc) || formatOf(phc) != FormatPHC {
+		t.Error("PHC string should not be passlib")
+	}
+}
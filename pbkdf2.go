@@ -3,6 +3,8 @@ package password
 import (
 	"crypto/sha1" // #nosec
 	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/base64"
 	"hash"
 	"strconv"
@@ -13,43 +15,77 @@ import (
 
 type pbkdf2Hasher struct {
 	algo      string
-	salt      string
+	salt      saltOption
 	iterCount int
+	format    string
 }
 
 func (hasher *pbkdf2Hasher) getSizeAndNew() (int, func() hash.Hash) {
+	return pbkdf2SizeAndNew(hasher.algo)
+}
+
+func pbkdf2SizeAndNew(algo string) (int, func() hash.Hash) {
 	var size int
 	var newfunc func() hash.Hash
 
-	switch hasher.algo {
+	switch algo {
 	case pbkdf2Sha256Algo:
 		size, newfunc = sha256.Size, sha256.New
-		break
 	case pbkdf2Sha1Algo:
 		size, newfunc = sha1.Size, sha1.New
-		break
+	case pbkdf2Sha512Algo:
+		size, newfunc = sha512.Size, sha512.New
 	}
 	return size, newfunc
 }
 
+// isPbkdf2 reports whether algo is supported by pbkdf2Hasher.
+func isPbkdf2(algo string) bool {
+	size, _ := pbkdf2SizeAndNew(algo)
+	return size > 0
+}
+
 func (hasher *pbkdf2Hasher) Encode(password string) (string, error) {
+	salt, err := hasher.salt.generate()
+	if err != nil {
+		return "", err
+	}
 	return hasher.encode(
 		hasher.algo,
+		hasher.format,
 		[]byte(password),
-		[]byte(hasher.salt),
+		[]byte(salt),
 		hasher.iterCount,
 	), nil
 }
 
 func (hasher *pbkdf2Hasher) Decode(encoded string) (*PasswordInfo, error) {
+	if isPasslib(encoded) {
+		return hasher.decodePasslib(encoded)
+	}
+	if isPHC(encoded) {
+		return hasher.decodePHC(encoded)
+	}
+	if isWerkzeug(encoded) {
+		return hasher.decodeWerkzeug(encoded)
+	}
+
 	parts := strings.SplitN(encoded, sep, 4)
+	if !isPbkdf2(parts[0]) {
+		return nil, errUnknownAlgorithmOf(parts[0])
+	}
+	if len(parts) != 4 {
+		return nil, ErrMalformedEncoded
+	}
 	iter, err := strconv.Atoi(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, errMalformed("iterations", err)
 	}
-
-	if parts[0] != pbkdf2Sha1Algo && parts[0] != pbkdf2Sha256Algo {
-		return nil, errUnknownAlgorithm
+	if iter < 1 {
+		return nil, errIllegalParam("iterations", "should be at least 1")
+	}
+	if hash, err := base64.StdEncoding.DecodeString(parts[3]); err != nil || len(hash) == 0 {
+		return nil, errMalformed("hash", err)
 	}
 
 	return &PasswordInfo{
@@ -60,46 +96,116 @@ func (hasher *pbkdf2Hasher) Decode(encoded string) (*PasswordInfo, error) {
 	}, nil
 }
 
-func (hasher *pbkdf2Hasher) Verify(password, encoded string) bool {
-	pi, err := hasher.Decode(encoded)
+// decodePHC decodes `$pbkdf2-sha256$i=10000$<b64salt>$<b64hash>`.
+func (hasher *pbkdf2Hasher) decodePHC(encoded string) (*PasswordInfo, error) {
+	p, err := parsePHC(encoded)
 	if err != nil {
-		return false
+		return nil, err
+	}
+	algo := phcIdentifiers[p.id]
+	if !isPbkdf2(algo) {
+		return nil, errUnknownAlgorithmOf(p.id)
+	}
+	iter, err := p.uintParam("i", 31)
+	if err != nil {
+		return nil, err
+	}
+	if iter < 1 {
+		return nil, errIllegalParam("i", "should be at least 1")
+	}
+	if len(p.hash) == 0 {
+		return nil, errMalformed("hash", nil)
 	}
 
-	return encoded == hasher.encode(pi.Algorithm, []byte(password), []byte(pi.Salt), pi.Iterations)
+	return &PasswordInfo{
+		Algorithm:  algo,
+		Iterations: int(iter),
+		Salt:       string(p.salt),
+		Hash:       base64.StdEncoding.EncodeToString(p.hash),
+	}, nil
 }
 
-func (hasher *pbkdf2Hasher) MustUpdate(encoded string) bool {
+// decodePasslib decodes `$pbkdf2-sha256$29000$<ab64salt>$<ab64hash>`, or
+// `{PBKDF2-SHA256}29000$<ab64salt>$<ab64hash>` of ldap_pbkdf2_sha256.
+func (hasher *pbkdf2Hasher) decodePasslib(encoded string) (*PasswordInfo, error) {
+	p, err := parsePasslib(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasswordInfo{
+		Algorithm:  p.algo,
+		Iterations: p.rounds,
+		Salt:       string(p.salt),
+		Hash:       base64.StdEncoding.EncodeToString(p.hash),
+	}, nil
+}
+
+// decodeWerkzeug decodes `pbkdf2:sha256:600000$salt$hexhash`.
+func (hasher *pbkdf2Hasher) decodeWerkzeug(encoded string) (*PasswordInfo, error) {
+	w, err := parseWerkzeug(encoded)
+	if err != nil {
+		return nil, err
+	}
+	algo := werkzeugAlgorithmOf(encoded)
+	if w.method != werkzeugPbkdf2 || !isPbkdf2(algo) {
+		return nil, errUnknownAlgorithmOf(algo)
+	}
+	if len(w.args) != 2 {
+		return nil, ErrMalformedEncoded
+	}
+	iter, err := strconv.Atoi(w.args[1])
+	if err != nil {
+		return nil, errMalformed("iterations", err)
+	}
+	if iter < 1 {
+		return nil, errIllegalParam("iterations", "should be at least 1")
+	}
+
+	return &PasswordInfo{
+		Algorithm:  algo,
+		Iterations: iter,
+		Salt:       w.salt,
+		Hash:       base64.StdEncoding.EncodeToString(w.hash),
+	}, nil
+}
+
+func (hasher *pbkdf2Hasher) Verify(password, encoded string) bool {
 	pi, err := hasher.Decode(encoded)
 	if err != nil {
 		return false
 	}
+	return verifyPbkdf2(password, pi)
+}
 
-	updateSalt := mustUpdateSalt(pi.Salt, saltEntropy)
-	return pi.Iterations < hasher.iterCount || updateSalt || len(hasher.salt) > len(pi.Salt)
+// verifyPbkdf2 verifies password with decoded pbkdf2 PasswordInfo.
+func verifyPbkdf2(password string, pi *PasswordInfo) bool {
+	hash, err := base64.StdEncoding.DecodeString(pi.Hash)
+	if err != nil || len(hash) == 0 {
+		return false
+	}
+
+	_, newFunc := pbkdf2SizeAndNew(pi.Algorithm)
+	key := pbkdf2.Key([]byte(password), []byte(pi.Salt), pi.Iterations, len(hash), newFunc)
+	return subtle.ConstantTimeCompare(key, hash) == 1
 }
 
-func (hasher *pbkdf2Hasher) Harden(password, encoded string) (string, error) {
+func (hasher *pbkdf2Hasher) MustUpdate(encoded string) bool {
 	pi, err := hasher.Decode(encoded)
 	if err != nil {
-		return "", err
+		return false
 	}
 
-	extraIterations := hasher.iterCount - pi.Iterations
-	if extraIterations > 0 {
-		return hasher.encode(
-			pi.Algorithm,
-			[]byte(password),
-			[]byte(pi.Salt),
-			extraIterations,
-		), nil
-	}
+	return pi.Algorithm != hasher.algo || pi.Iterations < hasher.iterCount || hasher.salt.mustUpdate(pi.Salt) ||
+		formatOf(encoded) != hasher.format
+}
 
-	return encoded, nil
+func (hasher *pbkdf2Hasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
 }
 
-func (hasher *pbkdf2Hasher) encode(algo string, password, salt []byte, iteration int) string {
-	size, newFunc := hasher.getSizeAndNew()
+func (hasher *pbkdf2Hasher) encode(algo, format string, password, salt []byte, iteration int) string {
+	size, newFunc := pbkdf2SizeAndNew(algo)
 	hash := pbkdf2.Key(
 		password,
 		salt,
@@ -107,6 +213,18 @@ func (hasher *pbkdf2Hasher) encode(algo string, password, salt []byte, iteration
 		size,
 		newFunc,
 	)
+
+	if format == FormatPHC {
+		return formatPHC(phcIdentifierOf(algo), "", "i="+strconv.Itoa(iteration), salt, hash)
+	}
+	if format == FormatPasslib {
+		return formatPasslib(algo, iteration, salt, hash)
+	}
+	if format == FormatWerkzeug {
+		method := []string{werkzeugPbkdf2, werkzeugDigestOf(algo), strconv.Itoa(iteration)}
+		return formatWerkzeug(method, string(salt), hash)
+	}
+
 	ss := []string{
 		algo,
 		strconv.Itoa(iteration),
@@ -119,7 +237,14 @@ func (hasher *pbkdf2Hasher) encode(algo string, password, salt []byte, iteration
 func newPBKDDF2Hasher(opt *HasherOption) (Hasher, error) {
 	return &pbkdf2Hasher{
 		algo:      opt.Algorithm,
-		salt:      opt.Salt,
+		salt:      newSaltOption(opt),
 		iterCount: opt.Iterations,
+		format:    defaultFormatOf(opt.Format),
 	}, nil
 }
+
+func init() {
+	mustRegisterHasher(pbkdf2Sha1Algo, newPBKDDF2Hasher)
+	mustRegisterHasher(pbkdf2Sha256Algo, newPBKDDF2Hasher)
+	mustRegisterHasher(pbkdf2Sha512Algo, newPBKDDF2Hasher)
+}
@@ -123,7 +123,8 @@ func TestMustUpdateForPbkdf2Sha256(t *testing.T) {
 
 	opt3 := HasherOption{
 		Algorithm:  pbkdf2Sha256Algo,
-		Salt:       "saltsaltsaltsaltsalt11",
+		Salt:       "saltsaltsalt",
+		SaltLength: 30,
 		Iterations: 10000,
 	}
 	hasher3, _ := NewHasher(&opt3)
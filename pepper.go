@@ -0,0 +1,251 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// pepperPrefix prefixes passwords encoded with a pepper:
+//
+//	pepper$<pepper id>$<encoded by the algorithm>
+const pepperPrefix = "pepper"
+
+// defaultPepperID is the pepper id of `HasherOption.Secret` if SecretID is blank.
+const defaultPepperID = "1"
+
+// Errors of peppers.
+var (
+	ErrUnknownPepper   = newError(CodeUnknownPepper, "unknown pepper")
+	ErrIllegalPepperID = newError(CodeIllegalPepperID, "pepper id cannot be blank or contain '$'")
+	ErrBlankPepper     = newError(CodeBlankPepper, "pepper cannot be blank")
+)
+
+// PepperProvider provides peppers, which are secret keys stored outside the
+// database, by their ids.
+//
+// The id of the current pepper is stored in encoded passwords, so old peppers
+// can still verify passwords after rotation, and `MustUpdate` reports true
+// for passwords encoded with them.
+type PepperProvider interface {
+	// Current returns the id and the pepper used to encode passwords.
+	Current() (id string, pepper []byte, err error)
+	// Pepper returns the pepper of id, or an error if id is unknown.
+	Pepper(id string) ([]byte, error)
+}
+
+type memoryPepperProvider struct {
+	current string
+	peppers map[string][]byte
+}
+
+// NewMemoryPepperProvider returns a PepperProvider holding peppers in memory.
+// current is the id of the pepper used to encode passwords, the others are
+// only used to verify passwords.
+func NewMemoryPepperProvider(current string, peppers map[string][]byte) (PepperProvider, error) {
+	p := &memoryPepperProvider{
+		current: current,
+		peppers: make(map[string][]byte, len(peppers)),
+	}
+	for id, pepper := range peppers {
+		if len(id) == 0 || strings.Contains(id, sep) {
+			return nil, ErrIllegalPepperID
+		}
+		if len(pepper) == 0 {
+			return nil, ErrBlankPepper
+		}
+		p.peppers[id] = pepper
+	}
+	if _, ok := p.peppers[current]; !ok {
+		return nil, ErrUnknownPepper
+	}
+	return p, nil
+}
+
+func (p *memoryPepperProvider) Current() (string, []byte, error) {
+	return p.current, p.peppers[p.current], nil
+}
+
+func (p *memoryPepperProvider) Pepper(id string) ([]byte, error) {
+	pepper, ok := p.peppers[id]
+	if !ok {
+		return nil, ErrUnknownPepper
+	}
+	return pepper, nil
+}
+
+// NewFilePepperProvider returns a PepperProvider loading peppers from a json
+// file, peppers are base64 encoded:
+//
+//	{"current": "2", "peppers": {"1": "b2xkIHBlcHBlcg==", "2": "bmV3IHBlcHBlcg=="}}
+func NewFilePepperProvider(path string) (PepperProvider, error) {
+	b, err := ioutil.ReadFile(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Current string            `json:"current"`
+		Peppers map[string][]byte `json:"peppers"`
+	}
+	if err = json.Unmarshal(b, &config); err != nil {
+		return nil, err
+	}
+	return NewMemoryPepperProvider(config.Current, config.Peppers)
+}
+
+// pepperProviderOf returns the PepperProvider of opt, nil if no pepper is configured.
+func pepperProviderOf(opt *HasherOption) (PepperProvider, error) {
+	if opt.Peppers != nil {
+		return opt.Peppers, nil
+	}
+	if len(opt.Secret) == 0 {
+		return nil, nil
+	}
+
+	current := opt.SecretID
+	if len(current) == 0 {
+		current = defaultPepperID
+	}
+	peppers := map[string][]byte{current: []byte(opt.Secret)}
+	for id, secret := range opt.OldSecrets {
+		if id == current {
+			continue
+		}
+		peppers[id] = []byte(secret)
+	}
+	return NewMemoryPepperProvider(current, peppers)
+}
+
+// pepperHasher applies HMAC-SHA256 keyed by a pepper to passwords before
+// they are encoded by the wrapped hasher.
+type pepperHasher struct {
+	hasher   Hasher
+	provider PepperProvider
+}
+
+// pepper returns base64 encoded HMAC, which is short enough for bcrypt and has no NUL bytes.
+func (hasher *pepperHasher) pepper(pepper []byte, password string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (hasher *pepperHasher) Encode(password string) (string, error) {
+	id, pepper, err := hasher.provider.Current()
+	if err != nil {
+		return "", err
+	}
+	encoded, err := hasher.hasher.Encode(hasher.pepper(pepper, password))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{pepperPrefix, id, encoded}, sep), nil
+}
+
+// Decode decodes encoded by the wrapped hasher, PepperID of PasswordInfo is
+// blank if encoded has no pepper.
+func (hasher *pepperHasher) Decode(encoded string) (*PasswordInfo, error) {
+	id, inner, err := splitPepper(encoded)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := hasher.hasher.Decode(inner)
+	if err != nil {
+		return nil, err
+	}
+	pi.PepperID = id
+	return pi, nil
+}
+
+// Verify verifies encoded with the pepper of its id. Passwords encoded
+// without pepper are verified as is.
+func (hasher *pepperHasher) Verify(password, encoded string) bool {
+	id, inner, err := splitPepper(encoded)
+	if err != nil {
+		return false
+	}
+	if len(id) == 0 {
+		return hasher.hasher.Verify(password, inner)
+	}
+
+	pepper, err := hasher.provider.Pepper(id)
+	if err != nil {
+		return false
+	}
+	return hasher.hasher.Verify(hasher.pepper(pepper, password), inner)
+}
+
+func (hasher *pepperHasher) verifyUpgrade(password, encoded string) (bool, bool) {
+	id, inner, err := splitPepper(encoded)
+	if err != nil {
+		return false, false
+	}
+	if len(id) > 0 {
+		pepper, err := hasher.provider.Pepper(id)
+		if err != nil {
+			return false, false
+		}
+		password = hasher.pepper(pepper, password)
+	}
+
+	ok, mustUpdate := verifyUpgrade(hasher.hasher, password, inner)
+	if !ok {
+		return false, false
+	}
+	current, _, err := hasher.provider.Current()
+	return true, mustUpdate || (err == nil && id != current)
+}
+
+// MustUpdate returns true if encoded has no pepper or an old pepper,
+// or the wrapped hasher wants to update it.
+func (hasher *pepperHasher) MustUpdate(encoded string) bool {
+	id, inner, err := splitPepper(encoded)
+	if err != nil {
+		return false
+	}
+	if _, err = hasher.hasher.Decode(inner); err != nil {
+		return false
+	}
+
+	current, _, err := hasher.provider.Current()
+	if err != nil {
+		return false
+	}
+	return id != current || hasher.hasher.MustUpdate(inner)
+}
+
+// Check returns ErrUnknownPepper if the pepper of encoded is unknown.
+func (hasher *pepperHasher) Check(password, encoded string) error {
+	id, _, err := splitPepper(encoded)
+	if err != nil {
+		return err
+	}
+	if len(id) > 0 {
+		if _, err := hasher.provider.Pepper(id); err != nil {
+			return err
+		}
+	}
+	return checkPassword(hasher, password, encoded)
+}
+
+func (hasher *pepperHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+// splitPepper splits encoded into pepper id and the password encoded by the
+// algorithm, id is blank if encoded has no pepper. Pepper prefix with blank
+// id is malformed.
+func splitPepper(encoded string) (string, string, error) {
+	if !strings.HasPrefix(encoded, pepperPrefix+sep) {
+		return "", encoded, nil
+	}
+	parts := strings.SplitN(encoded, sep, 3)
+	if len(parts) != 3 || len(parts[1]) == 0 {
+		return "", encoded, errMalformed("pepper", nil)
+	}
+	return parts[1], parts[2], nil
+}
@@ -0,0 +1,175 @@
+package password
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPepper(t *testing.T) {
+	opt := &HasherOption{
+		Algorithm:  pbkdf2Sha256Algo,
+		Iterations: 1000,
+		Secret:     "pepper",
+	}
+	hasher, err := NewHasher(opt)
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	encoded, err := hasher.Encode(password)
+	if err != nil {
+		t.Fatalf("Encode should be ok: %s", err)
+	}
+	if !strings.HasPrefix(encoded, "pepper$1$pbkdf2_sha256$") {
+		t.Errorf("encoded should contain pepper id: %s", encoded)
+	}
+
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode should be ok: %s", err)
+	}
+	if pi.PepperID != defaultPepperID || pi.Algorithm != pbkdf2Sha256Algo {
+		t.Errorf("wrong PasswordInfo: %+v", pi)
+	}
+
+	if !hasher.Verify(password, encoded) {
+		t.Error("Verify() should be true")
+	}
+	if hasher.Verify("wrong", encoded) {
+		t.Error("Verify(wrong) should be false")
+	}
+	if hasher.MustUpdate(encoded) {
+		t.Error("should not update")
+	}
+
+	// without pepper, the peppered password can't be verified
+	plain, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000})
+	_, inner, _ := splitPepper(encoded)
+	if plain.Verify(password, inner) {
+		t.Error("Verify() without pepper should be false")
+	}
+
+	// passwords encoded without pepper are verified and updated
+	unpeppered, _ := plain.Encode(password)
+	if !hasher.Verify(password, unpeppered) {
+		t.Error("Verify(unpeppered) should be true")
+	}
+	if !hasher.MustUpdate(unpeppered) {
+		t.Error("should update because of no pepper")
+	}
+
+	m, _ := NewPasswordManager(opt)
+	if algo, err := m.Identify(encoded); err != nil || algo != pbkdf2Sha256Algo {
+		t.Errorf("Identify should be %s: %s %s", pbkdf2Sha256Algo, algo, err)
+	}
+	if !m.Verify(password, encoded) {
+		t.Error("PasswordManager should verify peppered password")
+	}
+
+	// blank pepper id
+	blank := "pepper$$" + inner
+	if _, err = hasher.Decode(blank); !errors.Is(err, ErrMalformedEncoded) {
+		t.Errorf("Decode(%s) should be ErrMalformedEncoded: %s", blank, err)
+	}
+	if _, err = m.Identify(blank); !errors.Is(err, ErrMalformedEncoded) {
+		t.Errorf("Identify(%s) should be ErrMalformedEncoded: %s", blank, err)
+	}
+	if hasher.Verify(password, blank) || m.Verify(password, blank) {
+		t.Errorf("Verify(%s) should be false", blank)
+	}
+}
+
+func TestPepperRotation(t *testing.T) {
+	old, _ := NewHasher(&HasherOption{
+		Algorithm:  bcryptAlgo,
+		Iterations: 1,
+		Secret:     "old pepper",
+		SecretID:   "2023",
+	})
+	encoded, _ := old.Encode(password)
+
+	hasher, err := NewHasher(&HasherOption{
+		Algorithm:  bcryptAlgo,
+		Iterations: 1,
+		Secret:     "new pepper",
+		SecretID:   "2024",
+		OldSecrets: map[string]string{"2023": "old pepper"},
+	})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+	if !hasher.Verify(password, encoded) {
+		t.Error("Verify() with old pepper should be true")
+	}
+	if !hasher.MustUpdate(encoded) {
+		t.Error("should update because of old pepper")
+	}
+
+	encoded2, _ := hasher.Encode(password)
+	if !strings.HasPrefix(encoded2, "pepper$2024$") || hasher.MustUpdate(encoded2) {
+		t.Errorf("should be encoded with new pepper: %s", encoded2)
+	}
+
+	// retired pepper is removed
+	removed, _ := NewHasher(&HasherOption{
+		Algorithm:  bcryptAlgo,
+		Iterations: 1,
+		Secret:     "new pepper",
+		SecretID:   "2024",
+	})
+	if removed.Verify(password, encoded) {
+		t.Error("Verify() with unknown pepper should be false")
+	}
+}
+
+func TestPepperProvider(t *testing.T) {
+	if _, err := NewMemoryPepperProvider("1", map[string][]byte{"2": []byte("pepper")}); err != ErrUnknownPepper {
+		t.Errorf("NewMemoryPepperProvider should be ErrUnknownPepper: %s", err)
+	}
+	if _, err := NewMemoryPepperProvider("a$b", map[string][]byte{"a$b": []byte("pepper")}); err != ErrIllegalPepperID {
+		t.Errorf("NewMemoryPepperProvider should be ErrIllegalPepperID: %s", err)
+	}
+	if _, err := NewMemoryPepperProvider("1", map[string][]byte{"1": nil}); err != ErrBlankPepper {
+		t.Errorf("NewMemoryPepperProvider should be ErrBlankPepper: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "pepper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peppers.json")
+	config := `{"current": "2", "peppers": {"1": "b2xkIHBlcHBlcg==", "2": "bmV3IHBlcHBlcg=="}}`
+	if err = ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewFilePepperProvider(path)
+	if err != nil {
+		t.Fatalf("NewFilePepperProvider should be ok: %s", err)
+	}
+	id, pepper, _ := provider.Current()
+	if id != "2" || string(pepper) != "new pepper" {
+		t.Errorf("wrong current pepper: %s %s", id, pepper)
+	}
+	if pepper, _ = provider.Pepper("1"); string(pepper) != "old pepper" {
+		t.Errorf("wrong pepper: %s", pepper)
+	}
+	if _, err = provider.Pepper("3"); err != ErrUnknownPepper {
+		t.Errorf("Pepper(3) should be ErrUnknownPepper: %s", err)
+	}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: sha1Algo, Iterations: 1, Peppers: provider})
+	encoded, _ := hasher.Encode(password)
+	if !strings.HasPrefix(encoded, "pepper$2$sha1$") || !hasher.Verify(password, encoded) {
+		t.Errorf("should be encoded with pepper 2: %s", encoded)
+	}
+
+	if _, err = NewFilePepperProvider(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("NewFilePepperProvider(missing) should be error")
+	}
+}
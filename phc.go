@@ -0,0 +1,168 @@
+package password
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// PHC string format:
+//
+//	$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]
+//
+// See https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+const (
+	// FormatDefault encodes passwords as `<algorithm>$...`, the format of this package.
+	FormatDefault = ""
+	// FormatPHC encodes passwords in PHC string format, only argon2, scrypt and pbkdf2 support it.
+	FormatPHC = "phc"
+	// FormatDjango encodes passwords like Django, argon2 as `argon2$argon2id$v=19$...`,
+	// the others are the same as FormatDefault. unsalted_md5 does not support it.
+	FormatDjango = "django"
+	// FormatWerkzeug encodes passwords like Werkzeug of Flask, such as
+	// `pbkdf2:sha256:600000$salt$hexhash`, only scrypt and pbkdf2 support it.
+	FormatWerkzeug = "werkzeug"
+	// FormatPasslib encodes passwords like passlib, such as
+	// `$pbkdf2-sha256$29000$<ab64salt>$<ab64hash>`, only pbkdf2 supports it.
+	FormatPasslib = "passlib"
+)
+
+var supportFormats = map[string]struct{}{
+	FormatDefault:  {},
+	FormatPHC:      {},
+	FormatDjango:   {},
+	FormatWerkzeug: {},
+	FormatPasslib:  {},
+}
+
+// phcIdentifiers maps PHC identifiers to algorithms.
+var phcIdentifiers = map[string]string{
+	"argon2id":      argon2Algo,
+	"argon2i":       argon2iAlgo,
+	"argon2d":       argon2dAlgo,
+	"scrypt":        scryptAlgo,
+	"pbkdf2-sha256": pbkdf2Sha256Algo,
+	"pbkdf2-sha1":   pbkdf2Sha1Algo,
+	"pbkdf2-sha512": pbkdf2Sha512Algo,
+}
+
+// phcIdentifierOf returns the PHC identifier of algorithm.
+func phcIdentifierOf(algorithm string) string {
+	for id, algo := range phcIdentifiers {
+		if algo == algorithm {
+			return id
+		}
+	}
+	return ""
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+type phcHash struct {
+	id      string
+	version string
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+func isPHC(encoded string) bool {
+	return strings.HasPrefix(encoded, sep)
+}
+
+// formatOf returns the format of encoded, FormatDefault if it is the same in Django.
+func formatOf(encoded string) string {
+	if isPasslib(encoded) {
+		return FormatPasslib
+	}
+	if isPHC(encoded) {
+		return FormatPHC
+	}
+	if isDjangoArgon2(encoded) {
+		return FormatDjango
+	}
+	if isWerkzeug(encoded) {
+		return FormatWerkzeug
+	}
+	return FormatDefault
+}
+
+// defaultFormatOf returns FormatDefault for FormatDjango, which is the same
+// for algorithms except argon2.
+func defaultFormatOf(format string) string {
+	if format == FormatDjango {
+		return FormatDefault
+	}
+	return format
+}
+
+func parsePHC(encoded string) (*phcHash, error) {
+	if !isPHC(encoded) {
+		return nil, ErrMalformedEncoded
+	}
+	fields := strings.Split(encoded[len(sep):], sep)
+	p := &phcHash{id: fields[0], params: map[string]string{}}
+	if len(p.id) == 0 {
+		return nil, ErrMalformedEncoded
+	}
+	fields = fields[1:]
+
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "v=") {
+		p.version = fields[0][2:]
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && strings.Contains(fields[0], "=") {
+		for _, param := range strings.Split(fields[0], ",") {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 || len(kv[0]) == 0 {
+				return nil, ErrMalformedEncoded
+			}
+			p.params[kv[0]] = kv[1]
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) != 2 {
+		return nil, ErrMalformedEncoded
+	}
+	var err error
+	if p.salt, err = phcDecodeString(fields[0]); err != nil {
+		return nil, errMalformed("salt", err)
+	}
+	if p.hash, err = phcDecodeString(fields[1]); err != nil {
+		return nil, errMalformed("hash", err)
+	}
+	return p, nil
+}
+
+// uintParam returns the unsigned integer param `name`.
+func (p *phcHash) uintParam(name string, bitSize int) (uint64, error) {
+	v, ok := p.params[name]
+	if !ok {
+		return 0, errIllegalParam(name, "missing")
+	}
+	n, err := strconv.ParseUint(v, 10, bitSize)
+	if err != nil {
+		return 0, errMalformed(name, err)
+	}
+	return n, nil
+}
+
+// formatPHC returns PHC string, version is omitted if blank.
+func formatPHC(id, version, params string, salt, hash []byte) string {
+	parts := []string{"", id}
+	if len(version) > 0 {
+		parts = append(parts, "v="+version)
+	}
+	if len(params) > 0 {
+		parts = append(parts, params)
+	}
+	parts = append(parts, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(hash))
+	return strings.Join(parts, sep)
+}
+
+// phcDecodeString decodes base64 without padding, padded input is accepted as well.
+func phcDecodeString(s string) ([]byte, error) {
+	return phcEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePHC(t *testing.T) {
+	p, err := parsePHC("$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$aGFzaA")
+	if err != nil {
+		t.Fatalf("parsePHC should be ok: %s", err)
+	}
+	if p.id != "argon2id" || p.version != "19" {
+		t.Errorf("wrong id or version: %s %s", p.id, p.version)
+	}
+	if m, _ := p.uintParam("m", 32); m != 65536 {
+		t.Errorf("m should be 65536: %d", m)
+	}
+	if _, err = p.uintParam("x", 32); err == nil {
+		t.Error("uintParam(x) should be error")
+	}
+	if string(p.salt) != "somesalt" || string(p.hash) != "hash" {
+		t.Errorf("wrong salt or hash: %s %s", p.salt, p.hash)
+	}
+
+	p, err = parsePHC("$pbkdf2-sha256$i=1000$c2FsdA==$aGFzaA==")
+	if err != nil {
+		t.Fatalf("parsePHC(padded) should be ok: %s", err)
+	}
+	if p.version != "" || string(p.salt) != "salt" {
+		t.Errorf("wrong version or salt: %s %s", p.version, p.salt)
+	}
+
+	for _, encoded := range []string{
+		"argon2id$v=19$m=65536$c2FsdA$aGFzaA",
+		"$",
+		"$argon2id$v=19$m=65536",
+		"$argon2id$v=19$m$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=1$c2FsdA$aGFzaA$extra",
+		"$argon2id$v=19$m=1$!!!$aGFzaA",
+	} {
+		if _, err = parsePHC(encoded); err == nil {
+			t.Errorf("parsePHC(%s) should be error", encoded)
+		}
+	}
+}
+
+func TestPHCVectors(t *testing.T) {
+	data := []struct {
+		algorithm string
+		password  string
+		encoded   string
+	}{
+		{
+			algorithm: argon2iAlgo,
+			password:  "password",
+			encoded:   "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG",
+		},
+		{
+			algorithm: pbkdf2Sha1Algo,
+			password:  "password",
+			encoded:   "$pbkdf2-sha1$i=1$c2FsdA$DGDID5YfDnHzqbUkr2ASBi/gN6Y",
+		},
+		{
+			algorithm: pbkdf2Sha256Algo,
+			password:  "password",
+			encoded:   "$pbkdf2-sha256$i=4096$c2FsdA$xeR41ZKIyEGqUw22hFxMjZYok6ABzk4RpJY4c6qYE0o",
+		},
+	}
+
+	for _, d := range data {
+		t.Run(d.algorithm, func(t *testing.T) {
+			algo, err := Identify(d.encoded)
+			if err != nil || algo != d.algorithm {
+				t.Errorf("Identify should be %s: %s %s", d.algorithm, algo, err)
+			}
+			if !Verify(d.password, d.encoded) {
+				t.Error("Verify() should be true")
+			}
+			if Verify("wrong", d.encoded) {
+				t.Error("Verify(wrong) should be false")
+			}
+		})
+	}
+}
+
+func TestPHCFormat(t *testing.T) {
+	opts := []*HasherOption{
+		{Algorithm: argon2Algo, Iterations: 1, Params: &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		{Algorithm: argon2dAlgo, Iterations: 1, Params: &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		{Algorithm: scryptAlgo, Salt: "saltsaltsalt", Iterations: 1},
+		{Algorithm: pbkdf2Sha256Algo, Salt: "saltsaltsalt", Iterations: 1000},
+		{Algorithm: pbkdf2Sha1Algo, Salt: "saltsaltsalt", Iterations: 1000},
+	}
+
+	for _, opt := range opts {
+		t.Run(opt.Algorithm, func(t *testing.T) {
+			phcOpt := *opt
+			phcOpt.Format = FormatPHC
+			phcHasher, err := NewHasher(&phcOpt)
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			hasher, _ := NewHasher(opt)
+
+			encoded, err := phcHasher.Encode(password)
+			if err != nil {
+				t.Fatalf("Encode should be ok: %s", err)
+			}
+			if !strings.HasPrefix(encoded, sep+phcIdentifierOf(opt.Algorithm)+sep) {
+				t.Errorf("encoded should be PHC string: %s", encoded)
+			}
+
+			pi, err := phcHasher.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode should be ok: %s", err)
+			}
+			if pi.Algorithm != opt.Algorithm {
+				t.Errorf("Algorithm should be %s: %s", opt.Algorithm, pi.Algorithm)
+			}
+
+			legacy, _ := hasher.Encode(password)
+			for _, h := range []Hasher{phcHasher, hasher} {
+				if !h.Verify(password, encoded) || !h.Verify(password, legacy) {
+					t.Error("Verify() should accept both formats")
+				}
+				if h.Verify("wrong", encoded) {
+					t.Error("Verify(wrong) should be false")
+				}
+			}
+			if phcHasher.MustUpdate(encoded) {
+				t.Error("should not update")
+			}
+		})
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	if _, err := NewHasher(&HasherOption{Algorithm: md5Algo, Iterations: 1, Format: "non-format"}); err != ErrUnknownFormat {
+		t.Errorf("NewHasher(non-format) should be ErrUnknownFormat: %s", err)
+	}
+	for _, algo := range []string{md5Algo, sha1Algo, bcryptAlgo} {
+		opt := &HasherOption{Algorithm: algo, Salt: "salt", Iterations: 1, Format: FormatPHC}
+		if _, err := NewHasher(opt); err != ErrUnsupportedFormat {
+			t.Errorf("NewHasher(%s) should be ErrUnsupportedFormat: %s", algo, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package password
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Errors of RegisterHasher.
+var (
+	ErrBlankAlgorithm      = newError(CodeBlankAlgorithm, "algorithm cannot be blank or contain '$'")
+	ErrNilHasherFactory    = newError(CodeNilHasherFactory, "nil HasherFactory")
+	ErrAlgorithmRegistered = newError(CodeAlgorithmRegistered, "algorithm has been registered")
+	ErrReservedAlgorithm   = newError(CodeReservedAlgorithm, "algorithm is reserved")
+)
+
+// HasherFactory makes a Hasher from a validated HasherOption.
+type HasherFactory func(opt *HasherOption) (Hasher, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]HasherFactory
+}{
+	factories: make(map[string]HasherFactory),
+}
+
+// RegisterHasher registers a HasherFactory for algorithm, so that
+// `NewHasher` accepts it as `HasherOption.Algorithm`.
+//
+// Built-in algorithms are registered by the same way. "pepper" is reserved
+// for the prefix of peppered passwords.
+func RegisterHasher(algorithm string, factory HasherFactory) error {
+	if len(algorithm) == 0 || strings.Contains(algorithm, sep) {
+		return ErrBlankAlgorithm
+	}
+	if algorithm == pepperPrefix {
+		return ErrReservedAlgorithm
+	}
+	if factory == nil {
+		return ErrNilHasherFactory
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.factories[algorithm]; ok {
+		return ErrAlgorithmRegistered
+	}
+	registry.factories[algorithm] = factory
+	return nil
+}
+
+// mustRegisterHasher like `RegisterHasher`, panic if errors occurred.
+func mustRegisterHasher(algorithm string, factory HasherFactory) {
+	if err := RegisterHasher(algorithm, factory); err != nil {
+		panic(algorithm + ": " + err.Error())
+	}
+}
+
+// unregisterHasher removes the HasherFactory of algorithm, for tests.
+func unregisterHasher(algorithm string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.factories, algorithm)
+}
+
+// RegisteredAlgorithms returns all registered algorithms in order.
+func RegisteredAlgorithms() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	algorithms := make([]string, 0, len(registry.factories))
+	for algorithm := range registry.factories {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
+func lookupHasherFactory(algorithm string) (HasherFactory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	factory, ok := registry.factories[algorithm]
+	return factory, ok
+}
@@ -0,0 +1,101 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+type reversedHasher struct{}
+
+func (hasher *reversedHasher) Encode(password string) (string, error) {
+	r := []rune(password)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return "reversed" + sep + string(r), nil
+}
+
+func (hasher *reversedHasher) Decode(encoded string) (*PasswordInfo, error) {
+	parts := strings.SplitN(encoded, sep, 2)
+	if len(parts) != 2 || parts[0] != "reversed" {
+		return nil, ErrUnknownAlgorithm
+	}
+	return &PasswordInfo{Algorithm: parts[0], Hash: parts[1]}, nil
+}
+
+func (hasher *reversedHasher) Verify(password, encoded string) bool {
+	encoded2, _ := hasher.Encode(password)
+	return encoded2 == encoded
+}
+
+func (hasher *reversedHasher) MustUpdate(encoded string) bool {
+	return false
+}
+
+func (hasher *reversedHasher) Harden(password, encoded string) (string, error) {
+	return encoded, nil
+}
+
+func TestRegisterHasher(t *testing.T) {
+	factory := func(opt *HasherOption) (Hasher, error) {
+		return &reversedHasher{}, nil
+	}
+
+	if err := RegisterHasher("", factory); err != ErrBlankAlgorithm {
+		t.Errorf("RegisterHasher(blank) should be ErrBlankAlgorithm: %s", err)
+	}
+	if err := RegisterHasher("a$b", factory); err != ErrBlankAlgorithm {
+		t.Errorf("RegisterHasher(a$b) should be ErrBlankAlgorithm: %s", err)
+	}
+	if err := RegisterHasher("reversed", nil); err != ErrNilHasherFactory {
+		t.Errorf("RegisterHasher(nil) should be ErrNilHasherFactory: %s", err)
+	}
+	if err := RegisterHasher(pepperPrefix, factory); err != ErrReservedAlgorithm {
+		t.Errorf("RegisterHasher(pepper) should be ErrReservedAlgorithm: %s", err)
+	}
+	if err := RegisterHasher(md5Algo, factory); err != ErrAlgorithmRegistered {
+		t.Errorf("RegisterHasher(md5) should be ErrAlgorithmRegistered: %s", err)
+	}
+
+	if err := RegisterHasher("reversed", factory); err != nil {
+		t.Fatalf("RegisterHasher(reversed) should be ok: %s", err)
+	}
+	defer unregisterHasher("reversed")
+	if err := RegisterHasher("reversed", factory); err != ErrAlgorithmRegistered {
+		t.Errorf("RegisterHasher(reversed) twice should be ErrAlgorithmRegistered: %s", err)
+	}
+
+	found := false
+	for _, algorithm := range RegisteredAlgorithms() {
+		if algorithm == "reversed" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("RegisteredAlgorithms() should contain reversed")
+	}
+
+	hasher, err := NewHasher(&HasherOption{Algorithm: "reversed", Iterations: 1})
+	if err != nil {
+		t.Fatalf("NewHasher(reversed) should be ok: %s", err)
+	}
+	encoded, _ := hasher.Encode(password)
+	if !hasher.Verify(password, encoded) {
+		t.Error("Verify() should be true")
+	}
+	// hashers without Check are checked by Decode and Verify
+	if err = CheckWith(hasher, "wrong", encoded); err != ErrMismatch {
+		t.Errorf("CheckWith(wrong) should be ErrMismatch: %s", err)
+	}
+}
+
+func TestBuiltinAlgorithmsRegistered(t *testing.T) {
+	for _, algorithm := range []string{
+		md5Algo, unsaltedMd5Algo, pbkdf2Sha256Algo, pbkdf2Sha1Algo,
+		argon2Algo, bcryptAlgo, bcryptSha256Algo, scryptAlgo, sha1Algo,
+	} {
+		if _, ok := lookupHasherFactory(algorithm); !ok {
+			t.Errorf("%s should be registered", algorithm)
+		}
+	}
+}
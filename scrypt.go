@@ -1,63 +1,256 @@
 package password
 
 import (
+	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
+	"math/bits"
 	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/scrypt"
 )
 
+// ScryptParams scrypt parameters
+type ScryptParams struct {
+	// N: CPU/memory cost, a power of 2 greater than 1 and at most 2^24
+	N int `json:"n"`
+	// R: block size, at least 1, 128*N*R should be at most 1 GiB
+	R int `json:"r"`
+	// P: parallelism, between 1 and 1024, R*P should be less than 2^30
+	P int `json:"p"`
+	// KeyLength: length of hash in bytes, at least 16
+	KeyLength int `json:"key_length"`
+}
+
 const (
-	blockSize   = 1 << 3
-	maxMemory   = 0
-	parallelism = 1
-	workFactor  = 1 << 14
-	keyLen      = 64
+	minScryptKeyLength = 16
+	// upper bounds of parameters read from encoded passwords, scrypt takes
+	// 128*N*r bytes of memory and p times of the time
+	maxScryptLogN   = 24
+	maxScryptN      = 1 << maxScryptLogN
+	maxScryptMemory = 1 << 30
+	maxScryptP      = 1 << 10
 )
 
+var defaultScryptParams = &ScryptParams{
+	N:         1 << 14,
+	R:         8,
+	P:         1,
+	KeyLength: 64,
+}
+
+func (p *ScryptParams) validate() error {
+	if p.N <= 1 || p.N&(p.N-1) != 0 {
+		return errIllegalParam("n", "should be a power of 2 greater than 1")
+	}
+	if p.N > maxScryptN {
+		return errIllegalParam("n", "should be at most 2^"+strconv.Itoa(maxScryptLogN))
+	}
+	if p.R < 1 {
+		return errIllegalParam("r", "should be at least 1")
+	}
+	if 128*uint64(p.N)*uint64(p.R) > maxScryptMemory {
+		return errIllegalParam("r", "128*n*r should be at most 1 GiB")
+	}
+	if p.P < 1 {
+		return errIllegalParam("p", "should be at least 1")
+	}
+	if p.P > maxScryptP {
+		return errIllegalParam("p", "should be at most "+strconv.Itoa(maxScryptP))
+	}
+	if uint64(p.R)*uint64(p.P) >= 1<<30 {
+		return errIllegalParam("p", "r*p should be less than 2^30")
+	}
+	if p.KeyLength < minScryptKeyLength {
+		return errIllegalParam("key_length", "should be at least "+strconv.Itoa(minScryptKeyLength))
+	}
+	return nil
+}
+
+// weakerThan reports whether any cost of p is lower than other.
+func (p *ScryptParams) weakerThan(other *ScryptParams) bool {
+	return p.N < other.N || p.R < other.R || p.P < other.P || p.KeyLength < other.KeyLength
+}
+
+// parseScryptParams parses `HasherOption.Params` like `parseArgon2Params`.
+func parseScryptParams(v interface{}) (*ScryptParams, error) {
+	var params *ScryptParams
+	switch p := v.(type) {
+	case nil:
+		return defaultScryptParams, nil
+	case *ScryptParams:
+		params = p
+	case ScryptParams:
+		params = &p
+	default:
+		params = &ScryptParams{}
+		*params = *defaultScryptParams
+		if err := decodeParams(v, params); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
 type scryptHasher struct {
-	salt string
+	salt   saltOption
+	format string
+	params *ScryptParams
 }
 
 func (hasher *scryptHasher) Encode(password string) (string, error) {
-	return hasher.encode(password, hasher.salt)
+	salt, err := hasher.salt.generate()
+	if err != nil {
+		return "", err
+	}
+	return hasher.encode(password, salt, hasher.format, hasher.params)
 }
 
-func (hasher *scryptHasher) encode(password, salt string) (string, error) {
+func (hasher *scryptHasher) encode(password, salt, format string, params *ScryptParams) (string, error) {
 	dk, err := scrypt.Key(
 		[]byte(password),
 		[]byte(salt),
-		workFactor,
-		blockSize,
-		parallelism,
-		keyLen)
+		params.N,
+		params.R,
+		params.P,
+		params.KeyLength)
 	if err != nil {
 		return "", err
 	}
 
+	if format == FormatPHC {
+		p := fmt.Sprintf("ln=%d,r=%d,p=%d", bits.TrailingZeros(uint(params.N)), params.R, params.P)
+		return formatPHC(phcIdentifierOf(scryptAlgo), "", p, []byte(salt), dk), nil
+	}
+	if format == FormatWerkzeug {
+		method := []string{werkzeugScrypt, strconv.Itoa(params.N), strconv.Itoa(params.R), strconv.Itoa(params.P)}
+		return formatWerkzeug(method, salt, dk), nil
+	}
+
 	hash := base64.StdEncoding.EncodeToString(dk)
 	parts := []string{
 		scryptAlgo,
-		strconv.Itoa(workFactor),
-		hasher.salt,
-		strconv.Itoa(blockSize),
-		strconv.Itoa(parallelism),
+		strconv.Itoa(params.N),
+		salt,
+		strconv.Itoa(params.R),
+		strconv.Itoa(params.P),
 		hash,
 	}
 	return strings.Join(parts, sep), nil
 }
 
+// Decode decodes `scrypt$N$salt$r$p$hash`, PHC string, or Werkzeug format.
+// Others of PasswordInfo is *ScryptParams, key length is the length of hash.
 func (hasher *scryptHasher) Decode(encoded string) (*PasswordInfo, error) {
+	if isPHC(encoded) {
+		return hasher.decodePHC(encoded)
+	}
+	if isWerkzeug(encoded) {
+		return hasher.decodeWerkzeug(encoded)
+	}
+
 	parts := strings.SplitN(encoded, sep, 6)
 	if parts[0] != scryptAlgo {
-		return nil, errUnknownAlgorithm
+		return nil, errUnknownAlgorithmOf(parts[0])
+	}
+	if len(parts) != 6 {
+		return nil, ErrMalformedEncoded
+	}
+
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errMalformed("n", err)
+	}
+	r, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, errMalformed("r", err)
+	}
+	p, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return nil, errMalformed("p", err)
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, errMalformed("hash", err)
+	}
+
+	return newScryptPasswordInfo(parts[2], hash, n, r, p)
+}
+
+// decodePHC decodes `$scrypt$ln=14,r=8,p=1$<b64salt>$<b64hash>`.
+func (hasher *scryptHasher) decodePHC(encoded string) (*PasswordInfo, error) {
+	p, err := parsePHC(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if phcIdentifiers[p.id] != scryptAlgo {
+		return nil, errUnknownAlgorithmOf(p.id)
+	}
+
+	ln, err := p.uintParam("ln", 6)
+	if err != nil {
+		return nil, err
+	}
+	if ln > maxScryptLogN {
+		return nil, errIllegalParam("ln", "should be at most "+strconv.Itoa(maxScryptLogN))
+	}
+	r, err := p.uintParam("r", 31)
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := p.uintParam("p", 31)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScryptPasswordInfo(string(p.salt), p.hash, 1<<ln, int(r), int(parallelism))
+}
+
+// decodeWerkzeug decodes `scrypt:32768:8:1$salt$hexhash`.
+func (hasher *scryptHasher) decodeWerkzeug(encoded string) (*PasswordInfo, error) {
+	w, err := parseWerkzeug(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if w.method != werkzeugScrypt {
+		return nil, errUnknownAlgorithmOf(werkzeugAlgorithmOf(encoded))
+	}
+	if len(w.args) != 3 {
+		return nil, ErrMalformedEncoded
+	}
+	n, err := strconv.Atoi(w.args[0])
+	if err != nil {
+		return nil, errMalformed("n", err)
+	}
+	r, err := strconv.Atoi(w.args[1])
+	if err != nil {
+		return nil, errMalformed("r", err)
+	}
+	p, err := strconv.Atoi(w.args[2])
+	if err != nil {
+		return nil, errMalformed("p", err)
+	}
+
+	return newScryptPasswordInfo(w.salt, w.hash, n, r, p)
+}
+
+func newScryptPasswordInfo(salt string, hash []byte, n, r, p int) (*PasswordInfo, error) {
+	params := &ScryptParams{N: n, R: r, P: p, KeyLength: len(hash)}
+	if err := params.validate(); err != nil {
+		return nil, err
 	}
 
 	return &PasswordInfo{
-		Algorithm: scryptAlgo,
-		Hash:      parts[5],
-		Salt:      parts[2],
+		Algorithm:  scryptAlgo,
+		Hash:       base64.StdEncoding.EncodeToString(hash),
+		Salt:       salt,
+		Iterations: n,
+		Others:     params,
 	}, nil
 }
 
@@ -66,13 +259,22 @@ func (hasher *scryptHasher) Verify(password, encoded string) bool {
 	if err != nil {
 		return false
 	}
+	return verifyScrypt(password, pi)
+}
 
-	encoded2, err := hasher.encode(password, pi.Salt)
+// verifyScrypt verifies password with decoded scrypt PasswordInfo.
+func verifyScrypt(password string, pi *PasswordInfo) bool {
+	hash, err := base64.StdEncoding.DecodeString(pi.Hash)
 	if err != nil {
 		return false
 	}
 
-	return encoded2 == encoded
+	params := pi.Others.(*ScryptParams)
+	key, err := scrypt.Key([]byte(password), []byte(pi.Salt), params.N, params.R, params.P, len(hash))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, hash) == 1
 }
 
 func (hasher *scryptHasher) MustUpdate(encoded string) bool {
@@ -80,13 +282,26 @@ func (hasher *scryptHasher) MustUpdate(encoded string) bool {
 	if err != nil {
 		return false
 	}
-	return mustUpdateSalt(pi.Salt, saltEntropy) || len(pi.Salt) < len(hasher.salt)
+	return hasher.salt.mustUpdate(pi.Salt) ||
+		pi.Others.(*ScryptParams).weakerThan(hasher.params) ||
+		formatOf(encoded) != hasher.format
 }
 
 func (hasher *scryptHasher) Harden(password, encoded string) (string, error) {
-	return encoded, nil
+	return harden(hasher, password, encoded)
 }
 
 func newScryptHasher(opt *HasherOption) (Hasher, error) {
-	return &scryptHasher{salt: opt.Salt}, nil
+	if opt.Format == FormatPasslib {
+		return nil, ErrUnsupportedFormat
+	}
+	params, err := parseScryptParams(opt.Params)
+	if err != nil {
+		return nil, err
+	}
+	return &scryptHasher{salt: newSaltOption(opt), format: defaultFormatOf(opt.Format), params: params}, nil
+}
+
+func init() {
+	mustRegisterHasher(scryptAlgo, newScryptHasher)
 }
@@ -1,6 +1,10 @@
 package password
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestScrypt(t *testing.T) {
 	opt := &HasherOption{
@@ -24,8 +28,8 @@ func TestScrypt(t *testing.T) {
 		t.Error("Decode(wrongEncoded) should be err")
 	}
 
-	if err != errUnknownAlgorithm {
-		t.Errorf("Decode(wrongEncoded) err should be %s: %s", errUnknownAlgorithm, err)
+	if !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Decode(wrongEncoded) err should be %s: %s", ErrUnknownAlgorithm, err)
 	}
 
 	t.Logf("encoded password: %s", encoded)
@@ -66,7 +70,8 @@ func TestMustUpdateForScrypt(t *testing.T) {
 
 	opt2 := &HasherOption{
 		Algorithm:  scryptAlgo,
-		Salt:       "saltsaltsalt2",
+		Salt:       "saltsaltsalt",
+		SaltLength: 30,
 		Iterations: 1,
 	}
 	hasher, _ = NewHasher(opt2)
@@ -76,7 +81,8 @@ func TestMustUpdateForScrypt(t *testing.T) {
 
 	opt3 := &HasherOption{
 		Algorithm:  scryptAlgo,
-		Salt:       "saltsaltsal",
+		Salt:       "saltsaltsalt",
+		SaltLength: 16,
 		Iterations: 1,
 	}
 	hasher, _ = NewHasher(opt3)
@@ -84,3 +90,102 @@ func TestMustUpdateForScrypt(t *testing.T) {
 		t.Error("should not update because of shorter salt")
 	}
 }
+
+func TestScryptParams(t *testing.T) {
+	// RFC 7914
+	encoded := "scrypt$1024$NaCl$8$16$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA=="
+	phc := "$scrypt$ln=10,r=8,p=16$TmFDbA$/bq+HJ00cgB4VucZDQHp/nxq18vII3gw53N2Y0s3MWIurzDZLiKjiG/xCSedmDDaxyevuUqD7m2DYMvfoswGQA"
+
+	hasher, err := NewHasher(&HasherOption{
+		Algorithm:  scryptAlgo,
+		Salt:       "saltsaltsaltsalt",
+		Iterations: 1,
+		Params:     map[string]interface{}{"n": 1024, "r": 8, "p": 16},
+	})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, e := range []string{encoded, phc} {
+		pi, err := hasher.Decode(e)
+		if err != nil {
+			t.Fatalf("Decode(%s) should be ok: %s", e, err)
+		}
+		expected := ScryptParams{N: 1024, R: 8, P: 16, KeyLength: 64}
+		if p := pi.Others.(*ScryptParams); *p != expected {
+			t.Errorf("Params should be %+v: %+v", expected, *p)
+		}
+		if !hasher.Verify("password", e) {
+			t.Errorf("Verify(%s) should be true", e)
+		}
+	}
+
+	// stored params are used instead of the configured ones
+	defaultHasher, _ := NewHasher(&HasherOption{Algorithm: scryptAlgo, Iterations: 1})
+	if !defaultHasher.Verify("password", encoded) {
+		t.Error("Verify() should use stored params")
+	}
+	if !defaultHasher.MustUpdate(encoded) {
+		t.Error("should update because of lower N")
+	}
+
+	stronger, _ := NewHasher(&HasherOption{
+		Algorithm:  scryptAlgo,
+		Salt:       "saltsaltsaltsalt",
+		Iterations: 1,
+		Params:     &ScryptParams{N: 1024, R: 8, P: 16, KeyLength: 64},
+	})
+	encoded2, _ := stronger.Encode(password)
+	if hasher.MustUpdate(encoded2) {
+		t.Error("should not update because of same params")
+	}
+	if !strings.HasPrefix(encoded2, "scrypt$1024$") {
+		t.Errorf("encoded should contain params: %s", encoded2)
+	}
+}
+
+func TestIllegalScryptParams(t *testing.T) {
+	for _, params := range []interface{}{
+		map[string]interface{}{"n": 1000},
+		map[string]interface{}{"n": 1},
+		map[string]interface{}{"r": 0},
+		map[string]interface{}{"p": 0},
+		map[string]interface{}{"r": 1 << 15, "p": 1 << 15},
+		map[string]interface{}{"n": 1 << 25},
+		map[string]interface{}{"n": 1 << 20, "r": 16},
+		map[string]interface{}{"p": 1 << 11},
+		&ScryptParams{N: 1024, R: 8, P: 1, KeyLength: 8},
+	} {
+		opt := &HasherOption{Algorithm: scryptAlgo, Iterations: 1, Params: params}
+		if _, err := NewHasher(opt); err == nil {
+			t.Errorf("NewHasher(%v) should be error", params)
+		}
+	}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: scryptAlgo, Iterations: 1})
+	for _, encoded := range []string{
+		"scrypt$1000$salt$8$1$aGFzaA==",
+		"scrypt$1024$salt$r$1$aGFzaA==",
+		"scrypt$1024$salt$8$1",
+	} {
+		if _, err := hasher.Decode(encoded); err == nil {
+			t.Errorf("Decode(%s) should be error", encoded)
+		}
+	}
+
+	// oversized costs of encoded passwords must not reach scrypt.Key
+	for _, encoded := range []string{
+		"$scrypt$ln=40,r=8,p=1$c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$scrypt$ln=63,r=8,p=1$c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$scrypt$ln=21,r=8,p=1$c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"scrypt$1099511627776$salt$8$1$aGFzaGhhc2hoYXNoaGFzaA==",
+		"scrypt:16384:8:65536$salt$68617368686173686861736868617368",
+	} {
+		if _, err := hasher.Decode(encoded); !errors.Is(err, ErrIllegalParam) {
+			t.Errorf("Decode(%s) should be ErrIllegalParam: %s", encoded, err)
+		}
+		if hasher.Verify(password, encoded) {
+			t.Errorf("Verify(%s) should be false", encoded)
+		}
+	}
+}
@@ -2,30 +2,45 @@ package password
 
 import (
 	"crypto/sha1" // #nosec
+	"crypto/subtle"
 	"encoding/hex"
 	"strings"
 )
 
 type sha1Hasher struct {
-	salt string
+	salt saltOption
 }
 
 func (hasher *sha1Hasher) Encode(password string) (string, error) {
-	return hasher.encode(password, hasher.salt), nil
+	salt, err := hasher.salt.generate()
+	if err != nil {
+		return "", err
+	}
+	return hasher.encode(password, salt), nil
 }
 
 func (hasher *sha1Hasher) encode(password, salt string) string {
+	parts := []string{sha1Algo, salt, hex.EncodeToString(hasher.digest(password, salt))}
+	return strings.Join(parts, sep)
+}
+
+func (hasher *sha1Hasher) digest(password, salt string) []byte {
 	h := sha1.New() // #nosec
 	h.Write([]byte(salt))
 	h.Write([]byte(password))
-	parts := []string{sha1Algo, salt, hex.EncodeToString(h.Sum(nil))}
-	return strings.Join(parts, sep)
+	return h.Sum(nil)
 }
 
 func (hasher *sha1Hasher) Decode(encoded string) (*PasswordInfo, error) {
 	parts := strings.SplitN(encoded, sep, 3)
 	if parts[0] != sha1Algo {
-		return nil, errUnknownAlgorithm
+		return nil, errUnknownAlgorithmOf(parts[0])
+	}
+	if len(parts) != 3 {
+		return nil, ErrMalformedEncoded
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return nil, errMalformed("hash", err)
 	}
 
 	return &PasswordInfo{
@@ -41,8 +56,11 @@ func (hasher *sha1Hasher) Verify(password, encoded string) bool {
 		return false
 	}
 
-	encoded2 := hasher.encode(password, pi.Salt)
-	return encoded2 == encoded
+	hash, err := hex.DecodeString(pi.Hash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hasher.digest(password, pi.Salt), hash) == 1
 }
 
 func (hasher *sha1Hasher) MustUpdate(encoded string) bool {
@@ -50,17 +68,21 @@ func (hasher *sha1Hasher) MustUpdate(encoded string) bool {
 	if err != nil {
 		return false
 	}
-	return mustUpdateSalt(pi.Salt, saltEntropy) || len(pi.Salt) < len(hasher.salt)
+	return hasher.salt.mustUpdate(pi.Salt)
 }
 
 func (hasher *sha1Hasher) Harden(password, encoded string) (string, error) {
-	return encoded, nil
+	return harden(hasher, password, encoded)
 }
 
 func newSha1Hasher(opt *HasherOption) (Hasher, error) {
-	if len(opt.Salt) == 0 {
-		return nil, errBlankSalt
+	if defaultFormatOf(opt.Format) != FormatDefault {
+		return nil, ErrUnsupportedFormat
 	}
 
-	return &sha1Hasher{salt: opt.Salt}, nil
+	return &sha1Hasher{salt: newSaltOption(opt)}, nil
+}
+
+func init() {
+	mustRegisterHasher(sha1Algo, newSha1Hasher)
 }
@@ -9,14 +9,14 @@ func TestSha1WithNoSalt(t *testing.T) {
 		Iterations: 1,
 	}
 	hasher, err := NewHasher(&opt)
-	if err == nil {
-		t.Errorf("NewHasher should be eror")
-	}
-	if hasher != nil {
-		t.Errorf("NewHasher: hasher should be nil")
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
 	}
-	if err != errBlankSalt {
-		t.Errorf("err should be %s", errBlankSalt)
+
+	encoded, _ := hasher.Encode(password)
+	pi, _ := hasher.Decode(encoded)
+	if len(pi.Salt) != defaultSaltLength {
+		t.Errorf("salt should be random: %s", pi.Salt)
 	}
 }
 
@@ -47,7 +47,7 @@ func TestSha1(t *testing.T) {
 	if err != nil {
 		t.Errorf("Decode(encoded) should be nil: %s", err)
 	}
-	if pi == nil || pi.Algorithm != sha1Algo || pi.Salt != opt.Salt {
+	if pi == nil || pi.Algorithm != sha1Algo || len(pi.Salt) != defaultSaltLength {
 		t.Errorf("Decode(encoded) error: pi=%v", pi)
 	}
 
@@ -81,7 +81,8 @@ func TestMustUpdateForSha1(t *testing.T) {
 
 	opt2 := HasherOption{
 		Algorithm:  sha1Algo,
-		Salt:       "sha1sha1sha12",
+		Salt:       "sha1sha1sha1",
+		SaltLength: 30,
 		Iterations: 1,
 	}
 	hasher, _ = NewHasher(&opt2)
@@ -91,7 +92,8 @@ func TestMustUpdateForSha1(t *testing.T) {
 
 	opt3 := HasherOption{
 		Algorithm:  sha1Algo,
-		Salt:       "sha1sha1sha",
+		Salt:       "sha1sha1sha1",
+		SaltLength: 16,
 		Iterations: 1,
 	}
 	hasher, _ = NewHasher(&opt3)
@@ -0,0 +1,383 @@
+package password
+
+import (
+	"crypto/md5" // #nosec
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// springAlgo verifies and encodes passwords of Spring Security
+// DelegatingPasswordEncoder:
+//
+//	{<id>}<encoded by the encoder of id>
+//
+// bcrypt, pbkdf2, argon2, scrypt, MD5 and noop ids are supported. Passwords
+// are encoded by the delegate algorithm, which is Params of HasherOption.
+const springAlgo = "spring"
+
+const (
+	springPrefix = "{"
+	springSuffix = "}"
+
+	springBcrypt = "bcrypt"
+	springPbkdf2 = "pbkdf2"
+	springArgon2 = "argon2"
+	springScrypt = "scrypt"
+	springMD5    = "MD5"
+	springNoop   = "noop"
+
+	// springMD5Algo is the inner algorithm of {MD5}, which hashes
+	// md5(password+salt) while md5 of this package hashes md5(salt+password).
+	springMD5Algo = "spring_md5"
+)
+
+// springIDs maps ids of Spring Security to algorithms of this package,
+// noop is plain text.
+var springIDs = map[string]string{
+	springBcrypt: bcryptAlgo,
+	springPbkdf2: pbkdf2Sha256Algo,
+	springArgon2: argon2Algo,
+	springScrypt: scryptAlgo,
+	springMD5:    springMD5Algo,
+	springNoop:   springNoop,
+}
+
+// springPbkdf2Defaults are defaults of Pbkdf2PasswordEncoder, which are not
+// stored in encoded passwords, so they are told by the length of salt and
+// hash. The hash is 256 bits.
+var springPbkdf2Defaults = []struct {
+	algo       string
+	iterations int
+	saltLength int
+}{
+	// defaultsForSpringSecurity_v5_8
+	{pbkdf2Sha256Algo, 310000, 16},
+	// defaultsForSpringSecurity_v5_5
+	{pbkdf2Sha1Algo, 185000, 8},
+}
+
+const (
+	springPbkdf2HashLength = 32
+	springScryptSaltLength = 16
+)
+
+// SpringInfo is Others of PasswordInfo decoded from a Spring Security password.
+type SpringInfo struct {
+	// ID: id of the encoder, such as bcrypt
+	ID string
+	// Inner: PasswordInfo decoded by the algorithm of ID, Algorithm is noop
+	// and Hash is the password for noop
+	Inner *PasswordInfo
+}
+
+// springHasher encodes passwords with the delegate algorithm in the layout
+// of Spring Security. Passwords of other ids, MD5 and noop always must be
+// updated.
+type springHasher struct {
+	id       string
+	delegate Hasher
+}
+
+// isSpring reports whether encoded starts with `{<id>}` of a supported id.
+func isSpring(encoded string) bool {
+	id, _, ok := splitSpring(encoded)
+	if !ok {
+		return false
+	}
+	_, ok = springIDs[id]
+	return ok
+}
+
+// splitSpring splits `{<id>}<encoded>`.
+func splitSpring(encoded string) (id, rest string, ok bool) {
+	if !strings.HasPrefix(encoded, springPrefix) {
+		return "", "", false
+	}
+	end := strings.Index(encoded, springSuffix)
+	if end < 0 {
+		return "", "", false
+	}
+	return encoded[len(springPrefix):end], encoded[end+len(springSuffix):], true
+}
+
+func (hasher *springHasher) Encode(password string) (string, error) {
+	var encoded string
+	var err error
+	switch h := hasher.delegate.(type) {
+	case *bcryptHasher:
+		encoded, err = h.Encode(password)
+		encoded = strings.TrimPrefix(encoded, bcryptAlgo+sep)
+	case *argon2Hasher:
+		encoded, err = h.Encode(password)
+	case *scryptHasher:
+		encoded, err = encodeSpringScrypt(password, h.params)
+	case *pbkdf2Hasher:
+		encoded, err = encodeSpringPbkdf2(password, h.algo)
+	}
+	if err != nil {
+		return "", err
+	}
+	return springPrefix + hasher.id + springSuffix + encoded, nil
+}
+
+// encodeSpringScrypt encodes like SCryptPasswordEncoder:
+//
+//	$<hex of log2(N)<<16 | r<<8 | p>$<b64salt>$<b64hash>
+func encodeSpringScrypt(password string, params *ScryptParams) (string, error) {
+	salt, err := generateRandomBytes(springScryptSaltLength)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	p := uint64(bits.TrailingZeros(uint(params.N)))<<16 | uint64(params.R)<<8 | uint64(params.P)
+	return strings.Join([]string{
+		"",
+		strconv.FormatUint(p, 16),
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(key),
+	}, sep), nil
+}
+
+// encodeSpringPbkdf2 encodes like Pbkdf2PasswordEncoder: hex of salt and hash.
+func encodeSpringPbkdf2(password, algo string) (string, error) {
+	for _, d := range springPbkdf2Defaults {
+		if d.algo != algo {
+			continue
+		}
+		salt, err := generateRandomBytes(d.saltLength)
+		if err != nil {
+			return "", err
+		}
+		_, newFunc := pbkdf2SizeAndNew(algo)
+		key := pbkdf2.Key([]byte(password), salt, d.iterations, springPbkdf2HashLength, newFunc)
+		return hex.EncodeToString(append(salt, key...)), nil
+	}
+	return "", errUnknownAlgorithmOf(algo)
+}
+
+// Decode decodes `{<id>}<encoded>`, Others of PasswordInfo is *SpringInfo.
+func (hasher *springHasher) Decode(encoded string) (*PasswordInfo, error) {
+	id, rest, ok := splitSpring(encoded)
+	if !ok {
+		return nil, errUnknownAlgorithmOf(strings.SplitN(encoded, sep, 2)[0])
+	}
+
+	var inner *PasswordInfo
+	var err error
+	switch id {
+	case springBcrypt:
+		if !isBareBcrypt(rest) {
+			return nil, errMalformed("hash", nil)
+		}
+		inner, err = (&bcryptHasher{}).Decode(rest)
+	case springArgon2:
+		if !isPHC(rest) {
+			return nil, errMalformed("hash", nil)
+		}
+		inner, err = (&argon2Hasher{}).Decode(rest)
+	case springScrypt:
+		inner, err = decodeSpringScrypt(rest)
+	case springPbkdf2:
+		inner, err = decodeSpringPbkdf2(rest)
+	case springMD5:
+		inner, err = decodeSpringMD5(rest)
+	case springNoop:
+		inner = &PasswordInfo{Algorithm: springNoop, Hash: rest}
+	default:
+		return nil, errUnknownAlgorithmOf(springPrefix + id + springSuffix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasswordInfo{
+		Algorithm:  springAlgo,
+		Hash:       inner.Hash,
+		Iterations: inner.Iterations,
+		Salt:       inner.Salt,
+		Others:     &SpringInfo{ID: id, Inner: inner},
+	}, nil
+}
+
+// decodeSpringScrypt decodes `$<hex params>$<b64salt>$<b64hash>`.
+func decodeSpringScrypt(encoded string) (*PasswordInfo, error) {
+	parts := strings.Split(encoded, sep)
+	if len(parts) != 4 || len(parts[0]) != 0 {
+		return nil, ErrMalformedEncoded
+	}
+	p, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		return nil, errMalformed("params", err)
+	}
+	ln := p >> 16 & 0xffff
+	if ln > maxScryptLogN {
+		return nil, errIllegalParam("n", "should be at most 2^"+strconv.Itoa(maxScryptLogN))
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformed("salt", err)
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, errMalformed("hash", err)
+	}
+	return newScryptPasswordInfo(string(salt), hash, 1<<ln, int(p>>8&0xff), int(p&0xff))
+}
+
+// decodeSpringPbkdf2 decodes hex of salt and hash.
+func decodeSpringPbkdf2(encoded string) (*PasswordInfo, error) {
+	b, err := hex.DecodeString(encoded)
+	if err != nil {
+		return nil, errMalformed("hash", err)
+	}
+	for _, d := range springPbkdf2Defaults {
+		if len(b) == d.saltLength+springPbkdf2HashLength {
+			return &PasswordInfo{
+				Algorithm:  d.algo,
+				Iterations: d.iterations,
+				Salt:       string(b[:d.saltLength]),
+				Hash:       base64.StdEncoding.EncodeToString(b[d.saltLength:]),
+			}, nil
+		}
+	}
+	return nil, errMalformed("hash", nil)
+}
+
+// decodeSpringMD5 decodes `{salt}<hex hash>` of MessageDigestPasswordEncoder,
+// the salt is optional and includes the braces.
+func decodeSpringMD5(encoded string) (*PasswordInfo, error) {
+	var salt string
+	if strings.HasPrefix(encoded, springPrefix) {
+		end := strings.Index(encoded, springSuffix)
+		if end < 0 {
+			return nil, errMalformed("salt", nil)
+		}
+		salt, encoded = encoded[:end+len(springSuffix)], encoded[end+len(springSuffix):]
+	}
+	if hash, err := hex.DecodeString(encoded); err != nil || len(hash) != md5.Size {
+		return nil, errMalformed("hash", err)
+	}
+	return &PasswordInfo{Algorithm: springMD5Algo, Salt: salt, Hash: encoded}, nil
+}
+
+func (hasher *springHasher) Verify(password, encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+
+	info := pi.Others.(*SpringInfo)
+	_, rest, _ := splitSpring(encoded)
+	switch info.ID {
+	case springBcrypt:
+		return (&bcryptHasher{algo: bcryptAlgo}).Verify(password, rest)
+	case springArgon2:
+		return (&argon2Hasher{}).Verify(password, rest)
+	case springScrypt:
+		return verifyScrypt(password, info.Inner)
+	case springPbkdf2:
+		return verifyPbkdf2(password, info.Inner)
+	case springMD5:
+		d := md5.Sum([]byte(password + info.Inner.Salt)) // #nosec
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(d[:])), []byte(info.Inner.Hash)) == 1
+	case springNoop:
+		return subtle.ConstantTimeCompare([]byte(password), []byte(info.Inner.Hash)) == 1
+	}
+	return false
+}
+
+// MustUpdate returns true if id differs from the delegate's, or encoded is
+// weaker than the delegate.
+func (hasher *springHasher) MustUpdate(encoded string) bool {
+	pi, err := hasher.Decode(encoded)
+	if err != nil {
+		return false
+	}
+
+	info := pi.Others.(*SpringInfo)
+	if info.ID != hasher.id {
+		return true
+	}
+	_, rest, _ := splitSpring(encoded)
+	switch h := hasher.delegate.(type) {
+	case *bcryptHasher:
+		return info.Inner.Iterations < h.cost
+	case *argon2Hasher:
+		return h.MustUpdate(rest)
+	case *scryptHasher:
+		return info.Inner.Others.(*ScryptParams).weakerThan(h.params)
+	case *pbkdf2Hasher:
+		return info.Inner.Algorithm != h.algo
+	}
+	return true
+}
+
+func (hasher *springHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+// springIDOf returns the Spring Security id of algorithm, which can encode
+// passwords.
+func springIDOf(algorithm string) string {
+	switch algorithm {
+	case bcryptAlgo:
+		return springBcrypt
+	case argon2Algo, argon2iAlgo, argon2dAlgo:
+		return springArgon2
+	case scryptAlgo:
+		return springScrypt
+	case pbkdf2Sha256Algo, pbkdf2Sha1Algo:
+		return springPbkdf2
+	}
+	return ""
+}
+
+// newSpringHasher makes a spring hasher, Params of opt is the HasherOption
+// of the delegate algorithm, which is bcrypt, argon2id, argon2i, argon2d,
+// scrypt, or pbkdf2_sha256 and pbkdf2_sha1 with the defaults of Spring
+// Security.
+func newSpringHasher(opt *HasherOption) (Hasher, error) {
+	delegateOpt, err := parseHasherOptionParams("delegate", opt.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	id := springIDOf(delegateOpt.Algorithm)
+	if len(id) == 0 {
+		return nil, errIllegalParam("delegate", fmt.Sprintf("%s is not supported by Spring Security", delegateOpt.Algorithm))
+	}
+	if len(opt.Secret) > 0 || opt.Peppers != nil || len(delegateOpt.Secret) > 0 || delegateOpt.Peppers != nil {
+		return nil, errIllegalParam("secret", "is not supported by Spring Security")
+	}
+	if id == springPbkdf2 {
+		for _, d := range springPbkdf2Defaults {
+			if d.algo == delegateOpt.Algorithm && d.iterations != delegateOpt.Iterations {
+				return nil, errIllegalParam("iterations", fmt.Sprintf("should be %d for %s", d.iterations, d.algo))
+			}
+		}
+	}
+	if id == springArgon2 {
+		delegateOpt.Format = FormatPHC
+	}
+
+	delegate, err := NewHasher(delegateOpt)
+	if err != nil {
+		return nil, err
+	}
+	return &springHasher{id: id, delegate: delegate}, nil
+}
+
+func init() {
+	mustRegisterHasher(springAlgo, newSpringHasher)
+}
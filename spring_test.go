@@ -0,0 +1,143 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// encoded by DelegatingPasswordEncoder of "password"
+var springPasswords = []struct {
+	id      string
+	encoded string
+}{
+	{springBcrypt, "{bcrypt}$2a$10$dXJ3SW6G7P50lGmMkkmwe.20cQQubK3.HZWzG3YB1tlRy.fqvM/BG"},
+	{springPbkdf2, "{pbkdf2}101112131415161718191a1b1c1d1e1f24eb79bc04224ce234ca637c484009227c19cac3a02cdc284653938a00aded24"},
+	{springPbkdf2, "{pbkdf2}0001020304050607c18c42a363543f68645d57859f952d9e5b74a5302ef4c2199584b3b948016b76"},
+	{springArgon2, "{argon2}$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"},
+	{springScrypt, "{scrypt}$100801$EBESExQVFhcYGRobHB0eHw==$4oKecA8FWS11EjBq7IK5uV5fE8+0H3VknLaEtCcArLw="},
+	{springMD5, "{MD5}{thisissalt}2a4e7104c2780098f50ed5a84bb2323d"},
+	{springMD5, "{MD5}5f4dcc3b5aa765d61d8327deb882cf99"},
+	{springNoop, "{noop}password"},
+}
+
+func TestVerifySpring(t *testing.T) {
+	hasher, err := NewHasher(&HasherOption{
+		Algorithm:  springAlgo,
+		Iterations: 1,
+		Params:     &HasherOption{Algorithm: bcryptAlgo, Iterations: 10},
+	})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, d := range springPasswords {
+		pi, err := hasher.Decode(d.encoded)
+		if err != nil {
+			t.Fatalf("Decode(%s) should be ok: %s", d.encoded, err)
+		}
+		if info := pi.Others.(*SpringInfo); pi.Algorithm != springAlgo || info.ID != d.id {
+			t.Errorf("Decode(%s) should be %s: %+v", d.encoded, d.id, info)
+		}
+		if !hasher.Verify("password", d.encoded) {
+			t.Errorf("Verify(%s) should be true", d.encoded)
+		}
+		if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+			t.Errorf("Check(wrong, %s) should be ErrMismatch: %s", d.encoded, err)
+		}
+		if hasher.MustUpdate(d.encoded) != (d.id != springBcrypt) {
+			t.Errorf("MustUpdate(%s) should be %v", d.encoded, d.id != springBcrypt)
+		}
+
+		if algo, err := Identify(d.encoded); err != nil || algo != springAlgo {
+			t.Errorf("Identify(%s) should be spring: %s %s", d.encoded, algo, err)
+		}
+		if !Verify("password", d.encoded) {
+			t.Errorf("default PasswordManager should verify %s", d.encoded)
+		}
+	}
+
+	pi, _ := hasher.Decode(springPasswords[2].encoded)
+	if inner := pi.Others.(*SpringInfo).Inner; inner.Algorithm != pbkdf2Sha1Algo || inner.Iterations != 185000 {
+		t.Errorf("pbkdf2 of Spring Security 5.5 should be pbkdf2_sha1: %+v", inner)
+	}
+	// {MD5} hashes password+salt, which is not md5 of this package
+	pi, _ = hasher.Decode("{MD5}{thisissalt}2a4e7104c2780098f50ed5a84bb2323d")
+	if inner := pi.Others.(*SpringInfo).Inner; inner.Algorithm != springMD5Algo || inner.Salt != "{thisissalt}" {
+		t.Errorf("inner of {MD5} should be spring_md5: %+v", inner)
+	}
+}
+
+func TestEncodeSpring(t *testing.T) {
+	data := []struct {
+		opt    *HasherOption
+		prefix string
+	}{
+		{&HasherOption{Algorithm: bcryptAlgo, Iterations: 10}, "{bcrypt}$2a$10$"},
+		{&HasherOption{Algorithm: argon2Algo, Iterations: 1}, "{argon2}$argon2id$v=19$"},
+		{&HasherOption{Algorithm: scryptAlgo, Iterations: 1, Params: &ScryptParams{N: 1024, R: 8, P: 1, KeyLength: 32}}, "{scrypt}$a0801$"},
+		{&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 310000}, "{pbkdf2}"},
+	}
+	for _, d := range data {
+		t.Run(d.opt.Algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{Algorithm: springAlgo, Iterations: 1, Params: d.opt})
+			if err != nil {
+				t.Fatalf("NewHasher should be ok: %s", err)
+			}
+			encoded, err := hasher.Encode(password)
+			if err != nil || !strings.HasPrefix(encoded, d.prefix) {
+				t.Fatalf("encoded should start with %s: %s %s", d.prefix, encoded, err)
+			}
+			if !hasher.Verify(password, encoded) || hasher.MustUpdate(encoded) {
+				t.Error("Verify() should be true without update")
+			}
+			if d.opt.Format != FormatDefault {
+				t.Error("delegate option should not be modified")
+			}
+		})
+	}
+
+	pbkdf2, _ := NewHasher(&HasherOption{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 310000}})
+	bcrypt11, _ := NewHasher(&HasherOption{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: bcryptAlgo, Iterations: 11}})
+	encoded, _ := pbkdf2.Encode(password)
+	if !bcrypt11.MustUpdate(encoded) || !bcrypt11.MustUpdate(springPasswords[0].encoded) {
+		t.Error("should update other ids and lower cost")
+	}
+}
+
+func TestIllegalSpring(t *testing.T) {
+	options := []*HasherOption{
+		{Algorithm: springAlgo, Iterations: 1},
+		{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: md5Algo, Iterations: 1}},
+		{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1000}},
+		{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: bcryptAlgo, Iterations: 10, Secret: "pepper"}},
+	}
+	for _, opt := range options {
+		if _, err := NewHasher(opt); !errors.Is(err, ErrIllegalParam) {
+			t.Errorf("NewHasher(%+v) should be ErrIllegalParam: %s", opt.Params, err)
+		}
+	}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: springAlgo, Iterations: 1, Params: &HasherOption{Algorithm: bcryptAlgo, Iterations: 10}})
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"bcrypt$$2a$10$dXJ3SW6G7P50lGmMkkmwe.20cQQubK3.HZWzG3YB1tlRy.fqvM/BG", ErrUnknownAlgorithm},
+		{"{sha256}0123", ErrUnknownAlgorithm},
+		{"{bcrypt}bcrypt$$2a$10$dXJ3SW6G7P50lGmMkkmwe.20cQQubK3.HZWzG3YB1tlRy.fqvM/BG", ErrMalformedEncoded},
+		{"{argon2}argon2$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG", ErrMalformedEncoded},
+		{"{scrypt}$100801$EBESExQVFhcYGRobHB0eHw==", ErrMalformedEncoded},
+		{"{scrypt}$zz$EBESExQVFhcYGRobHB0eHw==$4oKe", ErrMalformedEncoded},
+		{"{scrypt}$100800$EBESExQVFhcYGRobHB0eHw==$4oKecA8FWS11EjBq7IK5uV5fE8+0H3VknLaEtCcArLw=", ErrIllegalParam},
+		{"{scrypt}$280801$EBESExQVFhcYGRobHB0eHw==$4oKecA8FWS11EjBq7IK5uV5fE8+0H3VknLaEtCcArLw=", ErrIllegalParam},
+		{"{pbkdf2}0001020304050607", ErrMalformedEncoded},
+		{"{MD5}{salt5f4dcc3b5aa765d61d8327deb882cf99", ErrMalformedEncoded},
+		{"{MD5}5f4dcc3b", ErrMalformedEncoded},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+}
@@ -7,9 +7,33 @@ import (
 
 const randomChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// saltEntropyOf returns entropy in bits of a random salt of `randomChars`.
+func saltEntropyOf(length int) float64 {
+	return float64(length) * math.Log2(float64(len(randomChars)))
+}
+
 func mustUpdateSalt(salt string, entropy int) bool {
-	clen := float64(len(randomChars))
-	return float64(len(salt))*math.Log2(clen) < float64(entropy)
+	return saltEntropyOf(len(salt)) < float64(entropy)
+}
+
+// generateSalt returns a random salt of `randomChars`, which can be stored
+// between separators.
+func generateSalt(length int) (string, error) {
+	salt := make([]byte, 0, length)
+	// reject bytes which make the distribution not uniform
+	limit := 256 - 256%len(randomChars)
+	for len(salt) < length {
+		b, err := generateRandomBytes(length)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(salt) < length {
+				salt = append(salt, randomChars[int(c)%len(randomChars)])
+			}
+		}
+	}
+	return string(salt), nil
 }
 
 func generateRandomBytes(n int) ([]byte, error) {
@@ -1,6 +1,9 @@
 package password
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestMustUpdateSalt(t *testing.T) {
 	data := []struct {
@@ -40,3 +43,21 @@ func TestMustUpdateSalt(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateSalt(t *testing.T) {
+	salt, err := generateSalt(22)
+	if err != nil {
+		t.Fatalf("generateSalt should be ok: %s", err)
+	}
+	if len(salt) != 22 {
+		t.Errorf("salt length should be 22: %s", salt)
+	}
+	if strings.Trim(salt, randomChars) != "" {
+		t.Errorf("salt should only contain randomChars: %s", salt)
+	}
+
+	salt2, _ := generateSalt(22)
+	if salt == salt2 {
+		t.Error("salts should be random")
+	}
+}
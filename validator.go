@@ -1,7 +1,6 @@
 package password
 
 import (
-	"errors"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -56,15 +55,15 @@ const maxLengthPassword = 32
 
 func (opt *ValidatorOption) validate() error {
 	if opt.MinLength <= 0 {
-		return errMin
+		return ErrIllegalMinLength
 	}
 
 	if opt.MaxLength > maxLengthPassword {
-		return errMax
+		return ErrIllegalMaxLength
 	}
 
 	if opt.MinLength > opt.MaxLength {
-		return errMinMax
+		return ErrMinMaxLength
 	}
 
 	return opt.loadCommonPasswords()
@@ -103,28 +102,33 @@ func (v *validator) Validate(password string) error {
 		}
 	}
 
+	var missing []string
 	if v.opt.RequireDigit && !hasDigit {
-		return v.error()
+		missing = append(missing, "digits")
 	}
 
 	if v.opt.RequireLowercase && !hasLowerLetter {
-		return v.error()
+		missing = append(missing, "lower letters")
 	}
 
 	if v.opt.RequireUppercase && !hasUpperLetter {
-		return v.error()
+		missing = append(missing, "upper letters")
 	}
 
 	if v.opt.RequirePunctuation && !hasPunct {
-		return v.error()
+		missing = append(missing, "punctuations")
 	}
 
 	if v.opt.RequireLetter && (!hasUpperLetter && !hasLowerLetter) {
-		return v.error()
+		missing = append(missing, "letters")
+	}
+
+	if len(missing) > 0 {
+		return v.error(missing)
 	}
 
 	if !v.validateCommonPasswords(password) {
-		return errCommon
+		return ErrCommonPassword
 	}
 
 	return nil
@@ -141,18 +145,15 @@ func (v *validator) validateCommonPasswords(password string) bool {
 }
 
 func (v *validator) validateLength(length int) error {
-	if length < int(v.opt.MinLength) {
-		return errMinLength(int(v.opt.MinLength))
-	}
-
-	if length > int(v.opt.MaxLength) {
-		return errMaxLength(int(v.opt.MaxLength))
+	if length < int(v.opt.MinLength) || length > int(v.opt.MaxLength) {
+		return &LengthError{Min: int(v.opt.MinLength), Max: int(v.opt.MaxLength), Actual: length}
 	}
 
 	return nil
 }
 
-func (v *validator) error() error {
+// error returns *RequirementError, whose message lists all requirements.
+func (v *validator) error(missing []string) error {
 	builder := strings.Builder{}
 	builder.WriteString("The password should contain ")
 
@@ -175,7 +176,7 @@ func (v *validator) error() error {
 	}
 
 	s := strings.TrimSpace(builder.String())
-	return errors.New(strings.Trim(s, ","))
+	return &RequirementError{Missing: missing, message: strings.Trim(s, ",")}
 }
 
 // NewValidator return a Validator
@@ -210,8 +210,8 @@ func TestValidatorError(t *testing.T) {
 					t.Errorf("The value of MaxLength should in error string.")
 				}
 			} else if d.option.CommonPasswords != nil {
-				if err != errCommon {
-					t.Errorf("errCommon should be returned.")
+				if err != ErrCommonPassword {
+					t.Errorf("ErrCommonPassword should be returned.")
 				}
 			} else if d.option.RequireDigit {
 				if !strings.Contains(err.Error(), "digit") {
@@ -0,0 +1,87 @@
+package password
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Werkzeug format of `werkzeug.security.generate_password_hash`:
+//
+//	pbkdf2:<digest>:<iterations>$<salt>$<hex hash>
+//	scrypt:<n>:<r>:<p>$<salt>$<hex hash>
+//
+// Werkzeug writes all arguments of the method, so methods without them are
+// not supported.
+const werkzeugSep = ":"
+
+const (
+	werkzeugPbkdf2 = "pbkdf2"
+	werkzeugScrypt = "scrypt"
+)
+
+// werkzeugDigests maps digests of Werkzeug pbkdf2 to algorithms.
+var werkzeugDigests = map[string]string{
+	"sha256": pbkdf2Sha256Algo,
+	"sha1":   pbkdf2Sha1Algo,
+	"sha512": pbkdf2Sha512Algo,
+}
+
+// werkzeugDigestOf returns the Werkzeug digest of pbkdf2 algorithm.
+func werkzeugDigestOf(algorithm string) string {
+	for digest, algo := range werkzeugDigests {
+		if algo == algorithm {
+			return digest
+		}
+	}
+	return ""
+}
+
+type werkzeugHash struct {
+	method string
+	args   []string
+	salt   string
+	hash   []byte
+}
+
+func isWerkzeug(encoded string) bool {
+	method := strings.SplitN(encoded, sep, 2)[0]
+	return strings.HasPrefix(method, werkzeugPbkdf2+werkzeugSep) ||
+		strings.HasPrefix(method, werkzeugScrypt+werkzeugSep)
+}
+
+// werkzeugAlgorithmOf returns the algorithm of Werkzeug encoded password,
+// or the unsupported method.
+func werkzeugAlgorithmOf(encoded string) string {
+	method := strings.Split(strings.SplitN(encoded, sep, 2)[0], werkzeugSep)
+	if method[0] == werkzeugScrypt {
+		return scryptAlgo
+	}
+	if algo, ok := werkzeugDigests[method[1]]; ok {
+		return algo
+	}
+	return method[0] + werkzeugSep + method[1]
+}
+
+func parseWerkzeug(encoded string) (*werkzeugHash, error) {
+	if !isWerkzeug(encoded) {
+		return nil, ErrMalformedEncoded
+	}
+	parts := strings.Split(encoded, sep)
+	if len(parts) != 3 {
+		return nil, ErrMalformedEncoded
+	}
+	method := strings.Split(parts[0], werkzeugSep)
+	if len(parts[1]) == 0 {
+		return nil, errMalformed("salt", nil)
+	}
+	hash, err := hex.DecodeString(parts[2])
+	if err != nil || len(hash) == 0 {
+		return nil, errMalformed("hash", err)
+	}
+	return &werkzeugHash{method: method[0], args: method[1:], salt: parts[1], hash: hash}, nil
+}
+
+// formatWerkzeug returns Werkzeug encoded password.
+func formatWerkzeug(method []string, salt string, hash []byte) string {
+	return strings.Join(method, werkzeugSep) + sep + salt + sep + hex.EncodeToString(hash)
+}
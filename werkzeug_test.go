@@ -0,0 +1,111 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// generated like `werkzeug.security.generate_password_hash("password")`
+var werkzeugPasswords = []struct {
+	algo    string
+	encoded string
+}{
+	{pbkdf2Sha256Algo, "pbkdf2:sha256:600000$XJUzfOGp3oJ3GlYi$97d36b583958f4e0667caa766e61dacfa6c26e47dd7f0740c4893ea053c0857e"},
+	{pbkdf2Sha1Algo, "pbkdf2:sha1:1000$Nx5Lz4eI$33c18864a02e383c630956e7ff49c9d1d5147eb6"},
+	{scryptAlgo, "scrypt:32768:8:1$hVYb3WSZzmeKYb3R$af0cc3aa43a6d7d3961d51301ffc8a21d0fc766f796d0ffdea5602c1fd4cdc2c1eb1386b63812152e6de69eb08f1f7cdfc96aa8dcbe824f19dc86907a8d26a58"},
+}
+
+func TestVerifyWerkzeug(t *testing.T) {
+	for _, d := range werkzeugPasswords {
+		t.Run(d.algo, func(t *testing.T) {
+			hasher, _ := NewHasher(&HasherOption{Algorithm: d.algo, Iterations: 1})
+			pi, err := hasher.Decode(d.encoded)
+			if err != nil || pi.Algorithm != d.algo {
+				t.Fatalf("Decode(werkzeug) should be ok: %+v %s", pi, err)
+			}
+			if !hasher.Verify("password", d.encoded) {
+				t.Error("Verify(werkzeug) should be true")
+			}
+			if err = CheckWith(hasher, "wrong", d.encoded); err != ErrMismatch {
+				t.Errorf("Check(wrong) should be ErrMismatch: %s", err)
+			}
+			if !hasher.MustUpdate(d.encoded) {
+				t.Error("should update werkzeug to the default format")
+			}
+
+			if algo, err := Identify(d.encoded); err != nil || algo != d.algo {
+				t.Errorf("Identify(werkzeug) should be %s: %s %s", d.algo, algo, err)
+			}
+			if !Verify("password", d.encoded) {
+				t.Error("default PasswordManager should verify werkzeug")
+			}
+		})
+	}
+
+	if _, err := Identify("pbkdf2:sha384:600000$salt$00"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("pbkdf2:sha384 should be unknown: %s", err)
+	}
+}
+
+func TestEncodeWerkzeug(t *testing.T) {
+	data := []struct {
+		algo   string
+		prefix string
+		params interface{}
+	}{
+		{pbkdf2Sha256Algo, "pbkdf2:sha256:1000$", nil},
+		{pbkdf2Sha1Algo, "pbkdf2:sha1:1000$", nil},
+		{scryptAlgo, "scrypt:1024:8:1$", &ScryptParams{N: 1024, R: 8, P: 1, KeyLength: 64}},
+	}
+	for _, d := range data {
+		t.Run(d.algo, func(t *testing.T) {
+			hasher, err := NewHasher(&HasherOption{Algorithm: d.algo, Iterations: 1000, Params: d.params, Format: FormatWerkzeug})
+			if err != nil {
+				t.Fatalf("NewHasher should support werkzeug format: %s", err)
+			}
+			encoded, _ := hasher.Encode(password)
+			if !strings.HasPrefix(encoded, d.prefix) {
+				t.Errorf("encoded should start with %s: %s", d.prefix, encoded)
+			}
+			if !hasher.Verify(password, encoded) || hasher.MustUpdate(encoded) {
+				t.Error("Verify() should be true without update")
+			}
+		})
+	}
+
+	for _, algo := range []string{argon2Algo, bcryptAlgo, md5Algo, sha1Algo} {
+		if _, err := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1, Format: FormatWerkzeug}); err != ErrUnsupportedFormat {
+			t.Errorf("%s should not support werkzeug format: %s", algo, err)
+		}
+	}
+}
+
+func TestIllegalWerkzeug(t *testing.T) {
+	hasher, _ := NewHasher(&HasherOption{Algorithm: pbkdf2Sha256Algo, Iterations: 1})
+	data := []struct {
+		encoded string
+		err     error
+	}{
+		{"pbkdf2:sha256:600000$salt", ErrMalformedEncoded},
+		{"pbkdf2:sha256$salt$00", ErrMalformedEncoded},
+		{"pbkdf2:sha256:x$salt$00", ErrMalformedEncoded},
+		{"pbkdf2:sha256:0$salt$00", ErrIllegalParam},
+		{"pbkdf2:sha256:1$$00", ErrMalformedEncoded},
+		{"pbkdf2:sha256:1$salt$xyz", ErrMalformedEncoded},
+		{"pbkdf2:md5:1$salt$00", ErrUnknownAlgorithm},
+		{"scrypt:1024:8:1$salt$00", ErrUnknownAlgorithm},
+	}
+	for _, d := range data {
+		if _, err := hasher.Decode(d.encoded); !errors.Is(err, d.err) {
+			t.Errorf("Decode(%s) should be %s: %s", d.encoded, d.err, err)
+		}
+	}
+
+	scrypt, _ := NewHasher(&HasherOption{Algorithm: scryptAlgo, Iterations: 1})
+	for _, encoded := range []string{"scrypt:1024:8$salt$00", "scrypt:1000:8:1$salt$00", "scrypt:x:8:1$salt$00"} {
+		if _, err := scrypt.Decode(encoded); err == nil {
+			t.Errorf("Decode(%s) should be error", encoded)
+		}
+	}
+}
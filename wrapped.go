@@ -0,0 +1,229 @@
+package password
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// wrappedAlgo hashes digests of legacy algorithms with a strong algorithm:
+//
+//	wrapped$<legacy algorithm>$<legacy salt>$<encoded by the strong algorithm>
+//
+// Legacy passwords can be wrapped without their plaintext, see `Wrap`.
+const wrappedAlgo = "wrapped"
+
+// ErrNotWrappedHasher hasher of Wrap is not made for the wrapped algorithm.
+var ErrNotWrappedHasher = newError(CodeNotWrappedHasher, "hasher cannot wrap passwords")
+
+// wrappedDigests computes hex digests of the legacy algorithms which can be wrapped.
+var wrappedDigests = map[string]func(password, salt string) string{
+	md5Algo: func(password, salt string) string {
+		return hex.EncodeToString((&md5Hasher{}).digest(password, salt))
+	},
+	unsaltedMd5Algo: func(password, salt string) string {
+		return hex.EncodeToString((&md5Hasher{}).digest(password, ""))
+	},
+	sha1Algo: func(password, salt string) string {
+		return hex.EncodeToString((&sha1Hasher{}).digest(password, salt))
+	},
+}
+
+// WrappedInfo is Others of PasswordInfo decoded from a wrapped password.
+type WrappedInfo struct {
+	// Inner: the legacy algorithm
+	Inner string
+	// InnerSalt: salt of the legacy algorithm, blank for unsalted_md5
+	InnerSalt string
+	// Outer: PasswordInfo of the strong algorithm
+	Outer *PasswordInfo
+}
+
+// wrappedHasher verifies wrapped passwords by replaying the legacy digest
+// first. Passwords are encoded by the outer hasher without wrapping, and
+// wrapped passwords always must be updated.
+type wrappedHasher struct {
+	// outer is nil for the default PasswordManager
+	outer Hasher
+}
+
+// outerHasher returns the outer hasher, which is the current default
+// PasswordManager if outer is nil, so `SetDefaultPasswordManager` applies to
+// wrapped passwords.
+func (hasher *wrappedHasher) outerHasher() Hasher {
+	if hasher.outer == nil {
+		return defaultManager()
+	}
+	return hasher.outer
+}
+
+func (hasher *wrappedHasher) Encode(password string) (string, error) {
+	return hasher.outerHasher().Encode(password)
+}
+
+// wrap encodes hex digest of a legacy algorithm with the outer hasher.
+func (hasher *wrappedHasher) wrap(inner, salt, digest string) (string, error) {
+	encoded, err := hasher.outerHasher().Encode(digest)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{wrappedAlgo, inner, salt, encoded}, sep), nil
+}
+
+// Decode decodes wrapped passwords, others are decoded by the outer hasher.
+// Others of PasswordInfo is *WrappedInfo for wrapped passwords.
+func (hasher *wrappedHasher) Decode(encoded string) (*PasswordInfo, error) {
+	if !isWrapped(encoded) {
+		return hasher.outerHasher().Decode(encoded)
+	}
+
+	parts := strings.SplitN(encoded, sep, 4)
+	if len(parts) != 4 {
+		return nil, ErrMalformedEncoded
+	}
+	if _, ok := wrappedDigests[parts[1]]; !ok {
+		return nil, errUnknownAlgorithmOf(parts[1])
+	}
+	outer, err := hasher.outerHasher().Decode(parts[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasswordInfo{
+		Algorithm:  wrappedAlgo,
+		Hash:       outer.Hash,
+		Iterations: outer.Iterations,
+		Salt:       outer.Salt,
+		Others:     &WrappedInfo{Inner: parts[1], InnerSalt: parts[2], Outer: outer},
+	}, nil
+}
+
+func (hasher *wrappedHasher) Verify(password, encoded string) bool {
+	if !isWrapped(encoded) {
+		return hasher.outerHasher().Verify(password, encoded)
+	}
+
+	parts := strings.SplitN(encoded, sep, 4)
+	if len(parts) != 4 {
+		return false
+	}
+	digest, ok := wrappedDigests[parts[1]]
+	if !ok {
+		return false
+	}
+	return hasher.outerHasher().Verify(digest(password, parts[2]), parts[3])
+}
+
+func (hasher *wrappedHasher) verifyUpgrade(password, encoded string) (bool, bool) {
+	if !isWrapped(encoded) {
+		return verifyUpgrade(hasher.outerHasher(), password, encoded)
+	}
+	ok := hasher.Verify(password, encoded)
+	return ok, ok
+}
+
+// MustUpdate returns true for wrapped passwords, so they are replaced by
+// passwords encoded by the outer hasher.
+func (hasher *wrappedHasher) MustUpdate(encoded string) bool {
+	if !isWrapped(encoded) {
+		return hasher.outerHasher().MustUpdate(encoded)
+	}
+	_, err := hasher.Decode(encoded)
+	return err == nil
+}
+
+func (hasher *wrappedHasher) Harden(password, encoded string) (string, error) {
+	return harden(hasher, password, encoded)
+}
+
+func isWrapped(encoded string) bool {
+	return strings.HasPrefix(encoded, wrappedAlgo+sep)
+}
+
+// Wrap re-encodes a legacy md5, unsalted_md5 or sha1 password, decoded into
+// pi, by hashing its digest with a strong algorithm, so legacy passwords can
+// be migrated without their plaintext.
+//
+// hasher is made from HasherOption with Algorithm "wrapped", or a
+// PasswordManager managing it. Wrapped passwords are verified by the same
+// hasher, and `MustUpdate` reports true for them.
+func Wrap(hasher Hasher, pi *PasswordInfo) (string, error) {
+	if m, ok := hasher.(*PasswordManager); ok {
+		hasher = m.hashers[wrappedAlgo]
+	}
+	w, ok := hasher.(*wrappedHasher)
+	if !ok {
+		return "", ErrNotWrappedHasher
+	}
+
+	if _, ok = wrappedDigests[pi.Algorithm]; !ok {
+		return "", errUnknownAlgorithmOf(pi.Algorithm)
+	}
+	if strings.Contains(pi.Salt, sep) {
+		return "", ErrIllegalSalt
+	}
+	hash, err := hex.DecodeString(pi.Hash)
+	if err != nil {
+		return "", err
+	}
+
+	salt := pi.Salt
+	if pi.Algorithm == unsaltedMd5Algo {
+		salt = ""
+	}
+	return w.wrap(pi.Algorithm, salt, hex.EncodeToString(hash))
+}
+
+// parseHasherOptionParams parses `HasherOption.Params` which is the
+// HasherOption of another algorithm, name is the param in errors. The result
+// is a copy which can be modified.
+func parseHasherOptionParams(name string, v interface{}) (*HasherOption, error) {
+	opt := &HasherOption{}
+	switch p := v.(type) {
+	case nil:
+		return nil, errIllegalParam(name, "missing")
+	case *HasherOption:
+		*opt = *p
+	case HasherOption:
+		*opt = p
+	default:
+		if err := decodeParams(v, opt); err != nil {
+			return nil, err
+		}
+	}
+	return opt, nil
+}
+
+// parseWrappedParams parses `HasherOption.Params` of the wrapped algorithm,
+// which is the HasherOption of the outer algorithm.
+func parseWrappedParams(v interface{}) (*HasherOption, error) {
+	opt, err := parseHasherOptionParams("outer", v)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.Algorithm == wrappedAlgo {
+		return nil, errIllegalParam("outer", "cannot be wrapped")
+	}
+	return opt, nil
+}
+
+// newWrappedHasher makes a wrapped hasher, Params of opt is the HasherOption
+// of the outer algorithm, which should set the pepper if any.
+func newWrappedHasher(opt *HasherOption) (Hasher, error) {
+	if len(opt.Secret) > 0 || opt.Peppers != nil {
+		return nil, errIllegalParam("secret", "should be set in the outer option")
+	}
+	outerOpt, err := parseWrappedParams(opt.Params)
+	if err != nil {
+		return nil, err
+	}
+	outer, err := NewHasher(outerOpt)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedHasher{outer: outer}, nil
+}
+
+func init() {
+	mustRegisterHasher(wrappedAlgo, newWrappedHasher)
+}
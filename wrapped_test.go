@@ -0,0 +1,147 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	outer := &HasherOption{
+		Algorithm:  argon2Algo,
+		Iterations: 1,
+		Params:     &Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	}
+	hasher, err := NewHasher(&HasherOption{Algorithm: wrappedAlgo, Iterations: 1, Params: outer})
+	if err != nil {
+		t.Fatalf("NewHasher should be ok: %s", err)
+	}
+
+	for _, algo := range []string{md5Algo, unsaltedMd5Algo, sha1Algo} {
+		legacy, _ := NewHasher(&HasherOption{Algorithm: algo, Iterations: 1})
+		encoded, _ := legacy.Encode(password)
+		pi, _ := legacy.Decode(encoded)
+
+		wrapped, err := Wrap(hasher, pi)
+		if err != nil {
+			t.Fatalf("Wrap(%s) should be ok: %s", algo, err)
+		}
+		if !strings.HasPrefix(wrapped, "wrapped$"+algo+"$"+pi.Salt+"$argon2id$") {
+			t.Errorf("wrapped should contain legacy algorithm and salt: %s", wrapped)
+		}
+
+		wpi, err := hasher.Decode(wrapped)
+		if err != nil {
+			t.Fatalf("Decode(%s) should be ok: %s", wrapped, err)
+		}
+		info := wpi.Others.(*WrappedInfo)
+		if wpi.Algorithm != wrappedAlgo || info.Inner != algo || info.InnerSalt != pi.Salt || info.Outer.Algorithm != argon2Algo {
+			t.Errorf("wrong PasswordInfo: %+v %+v", wpi, info)
+		}
+
+		if !hasher.Verify(password, wrapped) {
+			t.Errorf("Verify(%s) should be true", wrapped)
+		}
+		if hasher.Verify("wrong", wrapped) {
+			t.Errorf("Verify(wrong, %s) should be false", wrapped)
+		}
+		if !hasher.MustUpdate(wrapped) {
+			t.Error("wrapped password should be updated")
+		}
+		if !Verify(password, wrapped) {
+			t.Error("default PasswordManager should verify wrapped password")
+		}
+
+		hardened, err := hasher.Harden(password, wrapped)
+		if err != nil {
+			t.Fatalf("Harden should be ok: %s", err)
+		}
+		if !strings.HasPrefix(hardened, "argon2id$") || hasher.MustUpdate(hardened) || !hasher.Verify(password, hardened) {
+			t.Errorf("hardened should be plain argon2id: %s", hardened)
+		}
+	}
+}
+
+func TestWrapWithPasswordManager(t *testing.T) {
+	outer := &HasherOption{Algorithm: bcryptAlgo, Iterations: 10}
+	m, err := NewPasswordManager(
+		outer,
+		&HasherOption{Algorithm: wrappedAlgo, Iterations: 1, Params: map[string]interface{}{"algorithm": bcryptAlgo, "iterations": 10}},
+		&HasherOption{Algorithm: unsaltedMd5Algo, Iterations: 1},
+	)
+	if err != nil {
+		t.Fatalf("NewPasswordManager should be ok: %s", err)
+	}
+
+	encoded := "unsalted_md5$$5f4dcc3b5aa765d61d8327deb882cf99"
+	pi, _ := m.Decode(encoded)
+	wrapped, err := Wrap(m, pi)
+	if err != nil {
+		t.Fatalf("Wrap should be ok: %s", err)
+	}
+	if algo, _ := m.Identify(wrapped); algo != wrappedAlgo {
+		t.Errorf("Identify should be %s: %s", wrappedAlgo, algo)
+	}
+
+	ok, newEncoded, err := m.VerifyAndUpgrade("password", wrapped, nil)
+	if !ok || err != nil {
+		t.Fatalf("VerifyAndUpgrade should be ok: %s", err)
+	}
+	if !strings.HasPrefix(newEncoded, "bcrypt$") || m.MustUpdate(newEncoded) {
+		t.Errorf("wrapped password should be upgraded to bcrypt: %s", newEncoded)
+	}
+}
+
+func TestWrapWithDefaultPasswordManager(t *testing.T) {
+	hasher := defaultManager().hashers[wrappedAlgo]
+	m, err := NewPasswordManager(&HasherOption{Algorithm: bcryptAlgo, Iterations: 10, Secret: "pepper"})
+	if err != nil {
+		t.Fatalf("NewPasswordManager should be ok: %s", err)
+	}
+	defer SetDefaultPasswordManager(defaultManager())
+	SetDefaultPasswordManager(m)
+
+	// wrapped by the current default PasswordManager
+	pi, _ := (&md5Hasher{algo: unsaltedMd5Algo}).Decode("unsalted_md5$$5f4dcc3b5aa765d61d8327deb882cf99")
+	wrapped, err := Wrap(hasher, pi)
+	if err != nil || !strings.HasPrefix(wrapped, "wrapped$unsalted_md5$$pepper$") {
+		t.Fatalf("Wrap should use the peppered bcrypt: %s %s", wrapped, err)
+	}
+	if !hasher.Verify("password", wrapped) {
+		t.Errorf("Verify(%s) should be true", wrapped)
+	}
+}
+
+func TestIllegalWrap(t *testing.T) {
+	for _, opt := range []*HasherOption{
+		{Algorithm: wrappedAlgo, Iterations: 1},
+		{Algorithm: wrappedAlgo, Iterations: 1, Params: &HasherOption{Algorithm: wrappedAlgo, Iterations: 1}},
+		{Algorithm: wrappedAlgo, Iterations: 1, Params: &HasherOption{Algorithm: "unknown", Iterations: 1}},
+		{Algorithm: wrappedAlgo, Iterations: 1, Secret: "pepper", Params: &HasherOption{Algorithm: bcryptAlgo, Iterations: 10}},
+	} {
+		if _, err := NewHasher(opt); err == nil {
+			t.Errorf("NewHasher(%+v) should be error", opt)
+		}
+	}
+
+	hasher, _ := NewHasher(&HasherOption{Algorithm: wrappedAlgo, Iterations: 1, Params: &HasherOption{Algorithm: bcryptAlgo, Iterations: 10}})
+	if _, err := Wrap(hasher, &PasswordInfo{Algorithm: bcryptAlgo}); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Wrap(bcrypt) should be ErrUnknownAlgorithm: %s", err)
+	}
+	if _, err := Wrap(hasher, &PasswordInfo{Algorithm: md5Algo, Hash: "zz"}); err == nil {
+		t.Error("Wrap(illegal hash) should be error")
+	}
+	bcrypt, _ := NewHasher(&HasherOption{Algorithm: bcryptAlgo, Iterations: 10})
+	if _, err := Wrap(bcrypt, &PasswordInfo{Algorithm: md5Algo}); err != ErrNotWrappedHasher {
+		t.Errorf("Wrap with bcrypt hasher should be ErrNotWrappedHasher: %s", err)
+	}
+
+	for _, encoded := range []string{"wrapped$md5$salt", "wrapped$sha256$salt$bcrypt$abc"} {
+		if _, err := hasher.Decode(encoded); err == nil {
+			t.Errorf("Decode(%s) should be error", encoded)
+		}
+		if hasher.Verify(password, encoded) {
+			t.Errorf("Verify(%s) should be false", encoded)
+		}
+	}
+}